@@ -2,8 +2,13 @@ package main
 
 import (
 	"api/internal/config"
+	"api/internal/mail"
+	"api/internal/realtime"
+	"api/internal/reconcile"
 	"api/internal/repository"
 	"api/internal/router"
+	"api/internal/sales"
+	"context"
 	"log"
 
 	"github.com/robfig/cron/v3"
@@ -12,7 +17,7 @@ import (
 func init() {
 	config.LoadEnvs()
 	config.InitFirebase()
-	config.InitAwsClients()
+	config.InitStorage()
 }
 
 func main() {
@@ -21,6 +26,12 @@ func main() {
 	repository.Connect()
 	repository.Migrate()
 
+	// Entrega dos eventos em tempo real (NOTIFY de qualquer réplica)
+	go realtime.Listen(context.Background(), repository.DSN())
+
+	// Envio dos e-mails da outbox via SMTP
+	go mail.Work(context.Background())
+
 	// Seed previa dos dados:
 	if err := repository.Seed(); err != nil {
 		log.Fatalf("erro ao executar seed: %v", err)
@@ -28,7 +39,13 @@ func main() {
 
 	c := cron.New()
 	// This runs every day at 3:30 AM
-	c.AddFunc("30 3 * * *", config.CleanupS3AndDB)
+	c.AddFunc("30 3 * * *", reconcile.Scheduled)
+	// Expira as vendas não confirmadas pelo comprador, a cada 15 minutos
+	c.AddFunc("*/15 * * * *", sales.ExpirePending)
+	// Libera os anúncios cuja reserva terminou
+	c.AddFunc("*/15 * * * *", sales.ReleaseExpiredReservations)
+	// Expira as ofertas sem resposta
+	c.AddFunc("*/15 * * * *", sales.ExpireOffers)
 	c.Start()
 
 	r := router.New()
@@ -0,0 +1,151 @@
+// Package apperror defines the errors the API answers with: a stable,
+// machine-readable code, the HTTP status and a message in pt-BR and en.
+// Handlers report them with Abort and middleware.Errors renders the response.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// Supported languages, pt-BR is the default
+const (
+	LangPT = "pt-BR"
+	LangEN = "en"
+)
+
+// Error is an error the API can send to the client
+type Error struct {
+	Status  int
+	Code    string
+	message map[string]string
+	details map[string]any
+	cause   error
+}
+
+// define registers an error of the catalog. Messages may reference details as {name}.
+func define(status int, code, pt, en string) *Error {
+	return &Error{Status: status, Code: code, message: map[string]string{LangPT: pt, LangEN: en}}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %v", e.Code, e.cause)
+	}
+	return e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors by code, so errors.Is(err, apperror.ErrListingNotFound)
+// works on copies made by Wrap and With
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) clone() *Error {
+	c := *e
+	c.details = make(map[string]any, len(e.details))
+	for k, v := range e.details {
+		c.details[k] = v
+	}
+	return &c
+}
+
+// Wrap returns a copy of e carrying the underlying error. The cause is logged,
+// never sent to the client.
+func (e *Error) Wrap(err error) *Error {
+	c := e.clone()
+	c.cause = err
+	return c
+}
+
+// With returns a copy of e with a detail sent to the client and available to the message
+func (e *Error) With(key string, value any) *Error {
+	c := e.clone()
+	c.details[key] = value
+	return c
+}
+
+// Details returns the details sent to the client, nil if there are none
+func (e *Error) Details() map[string]any {
+	if len(e.details) == 0 {
+		return nil
+	}
+	return e.details
+}
+
+// Message returns the message in lang, with {name} placeholders filled from the details
+func (e *Error) Message(lang string) string {
+	msg, ok := e.message[lang]
+	if !ok {
+		msg = e.message[LangPT]
+	}
+	for k, v := range e.details {
+		msg = strings.ReplaceAll(msg, "{"+k+"}", fmt.Sprint(v))
+	}
+	return msg
+}
+
+// From converts any error to an *Error, unknown errors become ErrInternal
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternal.Wrap(err)
+}
+
+// Abort stops the handler chain and records err for middleware.Errors to render
+func Abort(c *gin.Context, err error) {
+	c.Abort()
+	_ = c.Error(err)
+}
+
+// InvalidBody wraps a binding error, listing the failed fields when it comes from validation
+func InvalidBody(err error) *Error {
+	appErr := ErrInvalidBody.Wrap(err)
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]gin.H, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, gin.H{"field": fe.Field(), "rule": fe.Tag()})
+		}
+		appErr = appErr.With("fields", fields)
+	}
+
+	return appErr
+}
+
+// InvalidParam reports an invalid query or path parameter
+func InvalidParam(name string) *Error {
+	return ErrInvalidParam.With("param", name)
+}
+
+// Language picks the response language from an Accept-Language header
+func Language(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		switch {
+		case strings.HasPrefix(tag, "pt"):
+			return LangPT
+		case strings.HasPrefix(tag, "en"):
+			return LangEN
+		}
+	}
+	return LangPT
+}
+
+// ErrInternal is also used for every error that is not an *Error
+var ErrInternal = define(http.StatusInternalServerError, "INTERNAL_ERROR",
+	"Erro interno do servidor. Tente novamente mais tarde.",
+	"Internal server error. Please try again later.")
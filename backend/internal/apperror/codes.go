@@ -0,0 +1,384 @@
+package apperror
+
+import "net/http"
+
+// Requisição
+var (
+	ErrInvalidBody = define(http.StatusBadRequest, "INVALID_BODY",
+		"Corpo da requisição inválido.",
+		"Invalid request body.")
+	ErrInvalidParam = define(http.StatusBadRequest, "INVALID_PARAM",
+		"Parâmetro `{param}` inválido.",
+		"Invalid `{param}` param.")
+	ErrMissingParam = define(http.StatusBadRequest, "MISSING_PARAM",
+		"O parâmetro `{param}` é obrigatório.",
+		"The `{param}` param is required.")
+	ErrInvalidPriceRange = define(http.StatusBadRequest, "INVALID_PRICE_RANGE",
+		"O preço mínimo não pode ser maior que o preço máximo.",
+		"`min_price` must not be greater than `max_price`.")
+	ErrSortRequiresQuery = define(http.StatusBadRequest, "SORT_REQUIRES_QUERY",
+		"A ordenação por relevância exige o parâmetro `q`.",
+		"`sort=relevance` requires the `q` param.")
+	ErrCursorRequiresRecentSort = define(http.StatusBadRequest, "CURSOR_REQUIRES_RECENT_SORT",
+		"A paginação por cursor exige `sort=recent`.",
+		"`cursor` pagination requires `sort=recent`.")
+)
+
+// Autenticação e permissões
+var (
+	ErrMissingToken = define(http.StatusUnauthorized, "MISSING_TOKEN",
+		"Token de autenticação ausente.",
+		"Missing authentication token.")
+	ErrInvalidTokenFormat = define(http.StatusUnauthorized, "INVALID_TOKEN_FORMAT",
+		"Formato do token inválido, use `Bearer <token>`.",
+		"Invalid token format, expected `Bearer <token>`.")
+	ErrInvalidToken = define(http.StatusUnauthorized, "INVALID_TOKEN",
+		"Token de autenticação inválido ou expirado.",
+		"Invalid or expired authentication token.")
+	ErrUserNotRegistered = define(http.StatusUnauthorized, "USER_NOT_REGISTERED",
+		"Usuário não cadastrado, faça login novamente.",
+		"User not registered, please log in again.")
+	ErrMissingPermission = define(http.StatusForbidden, "MISSING_PERMISSION",
+		"Você não tem permissão para realizar esta ação.",
+		"You don't have permission to perform this action.")
+	ErrEmailNotInstitutional = define(http.StatusForbidden, "EMAIL_NOT_INSTITUTIONAL",
+		"Para acessar o Sanca Brechó é necessário utilizar um e-mail de uma instituição de ensino superior.",
+		"Sanca Brechó requires an email from a higher education institution.")
+	ErrUserNotVerified = define(http.StatusForbidden, "USER_NOT_VERIFIED",
+		"Verifique sua conta para realizar esta ação.",
+		"Verify your account to perform this action.")
+	ErrEmailNotVerified = define(http.StatusForbidden, "EMAIL_NOT_VERIFIED",
+		"Confirme o seu e-mail institucional antes de verificar a conta.",
+		"Confirm your institutional email before verifying your account.")
+	ErrAlreadyVerified = define(http.StatusConflict, "ALREADY_VERIFIED",
+		"Sua conta já está verificada.",
+		"Your account is already verified.")
+	ErrVerificationRevoked = define(http.StatusForbidden, "VERIFICATION_REVOKED",
+		"A verificação da sua conta foi revogada pela moderação.",
+		"The verification of your account was revoked by the moderation.")
+	ErrVerificationCodeRecentlySent = define(http.StatusTooManyRequests, "VERIFICATION_CODE_RECENTLY_SENT",
+		"Aguarde {seconds} segundos para pedir um novo código.",
+		"Wait {seconds} seconds to request a new code.")
+	ErrInvalidVerificationCode = define(http.StatusBadRequest, "INVALID_VERIFICATION_CODE",
+		"Código de verificação incorreto.",
+		"Wrong verification code.")
+	ErrVerificationCodeExpired = define(http.StatusGone, "VERIFICATION_CODE_EXPIRED",
+		"O código de verificação expirou, peça um novo.",
+		"The verification code expired, request a new one.")
+	ErrAccountSuspended = define(http.StatusForbidden, "ACCOUNT_SUSPENDED",
+		"Sua conta está suspensa.",
+		"Your account is suspended.")
+	ErrAccountBanned = define(http.StatusForbidden, "ACCOUNT_BANNED",
+		"Sua conta foi banida.",
+		"Your account was banned.")
+)
+
+// Usuários
+var (
+	ErrUserNotFound = define(http.StatusNotFound, "USER_NOT_FOUND",
+		"Usuário não encontrado.",
+		"User not found.")
+	ErrInvalidRole = define(http.StatusBadRequest, "INVALID_ROLE",
+		"Cargo inválido.",
+		"Invalid role.")
+	ErrLastSuperadmin = define(http.StatusConflict, "LAST_SUPERADMIN",
+		"Este é o último superadmin, promova outro usuário antes.",
+		"This is the last superadmin, promote another user first.")
+	ErrAuthProviderFailure = define(http.StatusBadGateway, "AUTH_PROVIDER_FAILURE",
+		"Falha ao se comunicar com o serviço de autenticação.",
+		"Failed to reach the authentication service.")
+	ErrCannotSanctionSelf = define(http.StatusBadRequest, "CANNOT_SANCTION_SELF",
+		"Você não pode suspender ou banir a si mesmo.",
+		"You cannot suspend or ban yourself.")
+	ErrUserNotSuspended = define(http.StatusConflict, "USER_NOT_SUSPENDED",
+		"Este usuário não está suspenso.",
+		"This user is not suspended.")
+	ErrUserAlreadyBanned = define(http.StatusConflict, "USER_ALREADY_BANNED",
+		"Este usuário já está banido.",
+		"This user is already banned.")
+	ErrUserNotBanned = define(http.StatusConflict, "USER_NOT_BANNED",
+		"Este usuário não está banido.",
+		"This user is not banned.")
+)
+
+// Anúncios e categorias
+var (
+	ErrListingNotFound = define(http.StatusNotFound, "LISTING_NOT_FOUND",
+		"Anúncio não encontrado.",
+		"Listing not found.")
+	ErrNotListingOwner = define(http.StatusForbidden, "NOT_LISTING_OWNER",
+		"Este anúncio pertence a outro usuário.",
+		"This listing belongs to another user.")
+	ErrListingNotAvailable = define(http.StatusConflict, "LISTING_NOT_AVAILABLE",
+		"Este anúncio não está disponível.",
+		"This listing is not available.")
+	ErrListingHidden = define(http.StatusForbidden, "LISTING_HIDDEN",
+		"Este anúncio foi ocultado pela moderação.",
+		"This listing was hidden by the moderation.")
+	ErrListingReserved = define(http.StatusConflict, "LISTING_RESERVED",
+		"O anúncio está reservado, libere a reserva ou cancele a venda pendente antes.",
+		"The listing is reserved, release it or cancel the pending sale first.")
+	ErrListingReservedForAnother = define(http.StatusConflict, "LISTING_RESERVED_FOR_ANOTHER",
+		"Este anúncio está reservado para outro comprador.",
+		"This listing is reserved for another buyer.")
+	ErrNotReserved = define(http.StatusConflict, "LISTING_NOT_RESERVED",
+		"Este anúncio não está reservado.",
+		"This listing is not reserved.")
+	ErrInvalidReservationDuration = define(http.StatusBadRequest, "INVALID_RESERVATION_DURATION",
+		"A reserva deve durar entre 1 e {max} horas.",
+		"The reservation must last between 1 and {max} hours.")
+	ErrTooManyListings = define(http.StatusBadRequest, "TOO_MANY_LISTINGS",
+		"Você atingiu o limite de {max} anúncios ativos.",
+		"You cannot have more than {max} active listings.")
+	ErrTitleTooLong = define(http.StatusBadRequest, "TITLE_TOO_LONG",
+		"O título deve ter no máximo {max} caracteres.",
+		"The title must have at most {max} characters.")
+	ErrDescriptionTooLong = define(http.StatusBadRequest, "DESCRIPTION_TOO_LONG",
+		"A descrição deve ter no máximo {max} caracteres.",
+		"The description must have at most {max} characters.")
+	ErrFieldNotEditable = define(http.StatusBadRequest, "FIELD_NOT_EDITABLE",
+		"O campo `{field}` não pode ser alterado.",
+		"The `{field}` field cannot be changed.")
+	ErrInvalidListingStatus = define(http.StatusBadRequest, "INVALID_LISTING_STATUS",
+		"Status de anúncio inválido.",
+		"Invalid listing status.")
+	ErrCategoryNotFound = define(http.StatusNotFound, "CATEGORY_NOT_FOUND",
+		"Categoria não encontrada.",
+		"Category not found.")
+	ErrInvalidCategory = define(http.StatusBadRequest, "INVALID_CATEGORY",
+		"Categoria inválida.",
+		"Invalid category.")
+	ErrCategoryCycle = define(http.StatusBadRequest, "CATEGORY_CYCLE",
+		"Uma categoria não pode ser ancestral de si mesma.",
+		"A category cannot be its own ancestor.")
+)
+
+// Imagens e arquivos
+var (
+	ErrImageNotFound = define(http.StatusNotFound, "IMAGE_NOT_FOUND",
+		"Imagem não encontrada.",
+		"Image not found.")
+	ErrTooManyImages = define(http.StatusBadRequest, "TOO_MANY_IMAGES",
+		"Um anúncio pode ter no máximo {max} imagens.",
+		"A listing cannot have more than {max} images.")
+	ErrTooManyPendingUploads = define(http.StatusTooManyRequests, "TOO_MANY_PENDING_UPLOADS",
+		"Muitos envios pendentes, anexe-os ou aguarde que expirem.",
+		"Too many pending uploads, attach them or wait for them to expire.")
+	ErrUploadNotFound = define(http.StatusBadRequest, "UPLOAD_NOT_FOUND",
+		"Envio desconhecido ou expirado.",
+		"Unknown or expired upload key.")
+	ErrUnsupportedMediaType = define(http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE",
+		"Tipo de arquivo não suportado, use: {allowed}.",
+		"Unsupported file type, use one of: {allowed}.")
+	ErrFileTooLarge = define(http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE",
+		"Arquivo muito grande.",
+		"File too large.")
+	ErrImageTooLarge = define(http.StatusBadRequest, "IMAGE_DIMENSIONS_TOO_LARGE",
+		"As dimensões da imagem são grandes demais.",
+		"Image dimensions too large.")
+	ErrInvalidFile = define(http.StatusBadRequest, "INVALID_FILE",
+		"Envie o arquivo no campo `file`.",
+		"Send the file in the `file` field.")
+	ErrInvalidImageOrder = define(http.StatusBadRequest, "INVALID_IMAGE_ORDER",
+		"`image_ids` deve conter cada imagem do anúncio exatamente uma vez.",
+		"`image_ids` must list every image of the listing exactly once.")
+	ErrInvalidImagePosition = define(http.StatusBadRequest, "INVALID_IMAGE_POSITION",
+		"`order` deve estar entre 0 e {max}.",
+		"`order` must be between 0 and {max}.")
+	ErrFileNotFound = define(http.StatusNotFound, "FILE_NOT_FOUND",
+		"Arquivo não encontrado.",
+		"File not found.")
+	ErrInvalidUploadURL = define(http.StatusForbidden, "INVALID_UPLOAD_URL",
+		"URL de envio inválida ou expirada.",
+		"Invalid or expired upload URL.")
+	ErrContentTypeMismatch = define(http.StatusBadRequest, "CONTENT_TYPE_MISMATCH",
+		"O Content-Type não corresponde ao da URL de envio.",
+		"Content-Type does not match the upload URL.")
+)
+
+// Ofertas
+var (
+	ErrOfferNotFound = define(http.StatusNotFound, "OFFER_NOT_FOUND",
+		"Oferta não encontrada.",
+		"Offer not found.")
+	ErrListingNotNegotiable = define(http.StatusConflict, "LISTING_NOT_NEGOTIABLE",
+		"O preço deste anúncio não é negociável.",
+		"The price of this listing is not negotiable.")
+	ErrCannotOfferOwnListing = define(http.StatusBadRequest, "CANNOT_OFFER_OWN_LISTING",
+		"Você não pode fazer uma oferta no seu próprio anúncio.",
+		"You cannot make an offer on your own listing.")
+	ErrInvalidOfferAmount = define(http.StatusBadRequest, "INVALID_OFFER_AMOUNT",
+		"O valor da oferta deve ser maior que zero.",
+		"The offer amount must be greater than zero.")
+	ErrOfferAlreadyOpen = define(http.StatusConflict, "OFFER_ALREADY_OPEN",
+		"Você já tem uma oferta em aberto neste anúncio.",
+		"You already have an open offer on this listing.")
+	ErrNotOfferBuyer = define(http.StatusForbidden, "NOT_OFFER_BUYER",
+		"Apenas o comprador pode retirar a oferta.",
+		"Only the buyer can withdraw the offer.")
+	ErrOfferNotOpen = define(http.StatusConflict, "OFFER_NOT_OPEN",
+		"Esta oferta não está mais em aberto.",
+		"This offer is no longer open.")
+	ErrOfferNotYourTurn = define(http.StatusConflict, "OFFER_NOT_YOUR_TURN",
+		"A última proposta é sua, aguarde a resposta da outra parte.",
+		"The last proposal is yours, wait for the other party to answer.")
+)
+
+// Vendas e avaliações
+var (
+	ErrSaleNotFound = define(http.StatusNotFound, "SALE_NOT_FOUND",
+		"Venda não encontrada.",
+		"Sale not found.")
+	ErrBuyerNotFound = define(http.StatusNotFound, "BUYER_NOT_FOUND",
+		"Comprador não encontrado.",
+		"Buyer not found.")
+	ErrNotSaleBuyer = define(http.StatusForbidden, "NOT_SALE_BUYER",
+		"Apenas o comprador pode realizar esta ação.",
+		"Only the buyer can perform this action.")
+	ErrNotSaleSeller = define(http.StatusForbidden, "NOT_SALE_SELLER",
+		"Apenas o vendedor pode realizar esta ação.",
+		"Only the seller can perform this action.")
+	ErrInvalidFinalPrice = define(http.StatusBadRequest, "INVALID_FINAL_PRICE",
+		"O preço final não pode ser negativo.",
+		"The final price cannot be negative.")
+	ErrCannotSellToSelf = define(http.StatusBadRequest, "CANNOT_SELL_TO_SELF",
+		"Você não pode ser o comprador do seu próprio anúncio.",
+		"You cannot be the buyer of your own listing.")
+	ErrSaleNotPending = define(http.StatusConflict, "SALE_NOT_PENDING",
+		"Esta venda não está mais aguardando resposta.",
+		"This sale is no longer waiting for an answer.")
+	ErrSaleExpired = define(http.StatusConflict, "SALE_EXPIRED",
+		"O prazo para responder a esta venda expirou.",
+		"The deadline to answer this sale has passed.")
+	ErrSaleNotConfirmed = define(http.StatusConflict, "SALE_NOT_CONFIRMED",
+		"Apenas vendas confirmadas pelo comprador podem ser avaliadas.",
+		"Only sales confirmed by the buyer can be reviewed.")
+	ErrReviewAlreadyExists = define(http.StatusConflict, "REVIEW_ALREADY_EXISTS",
+		"Você já avaliou esta venda.",
+		"You have already reviewed this sale.")
+	ErrNotSaleParticipant = define(http.StatusForbidden, "NOT_SALE_PARTICIPANT",
+		"Apenas o comprador e o vendedor podem avaliar esta venda.",
+		"Only the buyer and the seller can review this sale.")
+	ErrReviewNotFound = define(http.StatusNotFound, "REVIEW_NOT_FOUND",
+		"Avaliação não encontrada.",
+		"Review not found.")
+	ErrNotReviewedUser = define(http.StatusForbidden, "NOT_REVIEWED_USER",
+		"Apenas o usuário avaliado pode responder a esta avaliação.",
+		"Only the reviewed user can reply to this review.")
+	ErrReplyAlreadyExists = define(http.StatusConflict, "REPLY_ALREADY_EXISTS",
+		"Esta avaliação já foi respondida.",
+		"This review already has a reply.")
+	ErrNotReviewAuthor = define(http.StatusForbidden, "NOT_REVIEW_AUTHOR",
+		"Apenas o autor pode alterar esta avaliação.",
+		"Only the author can change this review.")
+	ErrReviewEditWindowClosed = define(http.StatusForbidden, "REVIEW_EDIT_WINDOW_CLOSED",
+		"O prazo para alterar esta avaliação já acabou.",
+		"The time to change this review is over.")
+	ErrReviewHidden = define(http.StatusConflict, "REVIEW_HIDDEN",
+		"Esta avaliação foi ocultada pela moderação.",
+		"This review was hidden by the moderation.")
+	ErrReviewNotHidden = define(http.StatusConflict, "REVIEW_NOT_HIDDEN",
+		"Esta avaliação não está oculta.",
+		"This review is not hidden.")
+	ErrReviewReported = define(http.StatusConflict, "REVIEW_REPORTED",
+		"Esta avaliação tem uma denúncia em análise e não pode ser apagada.",
+		"This review has an open report and cannot be deleted.")
+)
+
+// Mensagens
+var (
+	ErrConversationNotFound = define(http.StatusNotFound, "CONVERSATION_NOT_FOUND",
+		"Conversa não encontrada.",
+		"Conversation not found.")
+	ErrConversationClosed = define(http.StatusConflict, "CONVERSATION_CLOSED",
+		"O anúncio foi vendido ou removido, a conversa está encerrada.",
+		"The listing was sold or removed, the conversation is closed.")
+	ErrCannotMessageSelf = define(http.StatusBadRequest, "CANNOT_MESSAGE_OWN_LISTING",
+		"Você não pode iniciar uma conversa no seu próprio anúncio.",
+		"You cannot start a conversation on your own listing.")
+	ErrUserBlocked = define(http.StatusForbidden, "USER_BLOCKED",
+		"Não é possível trocar mensagens com este usuário.",
+		"You cannot exchange messages with this user.")
+	ErrCannotBlockSelf = define(http.StatusBadRequest, "CANNOT_BLOCK_SELF",
+		"Você não pode bloquear a si mesmo.",
+		"You cannot block yourself.")
+	ErrEmptyMessage = define(http.StatusBadRequest, "EMPTY_MESSAGE",
+		"A mensagem não pode estar vazia.",
+		"The message cannot be empty.")
+	ErrMessageTooLong = define(http.StatusBadRequest, "MESSAGE_TOO_LONG",
+		"A mensagem deve ter no máximo {max} caracteres.",
+		"The message must have at most {max} characters.")
+)
+
+// Notificações
+var (
+	ErrNotificationNotFound = define(http.StatusNotFound, "NOTIFICATION_NOT_FOUND",
+		"Notificação não encontrada.",
+		"Notification not found.")
+	ErrInvalidNotificationType = define(http.StatusBadRequest, "INVALID_NOTIFICATION_TYPE",
+		"Tipo de notificação inválido.",
+		"Invalid notification type.")
+	ErrInvalidNotificationChannel = define(http.StatusBadRequest, "INVALID_NOTIFICATION_CHANNEL",
+		"Canal de notificação inválido.",
+		"Invalid notification channel.")
+)
+
+// Favoritos e denúncias
+var (
+	ErrAlreadyFavorited = define(http.StatusConflict, "ALREADY_FAVORITED",
+		"Este anúncio já está nos favoritos.",
+		"This listing is already a favorite.")
+	ErrReportNotFound = define(http.StatusNotFound, "REPORT_NOT_FOUND",
+		"Denúncia não encontrada.",
+		"Report not found.")
+	ErrInvalidReportStatus = define(http.StatusBadRequest, "INVALID_REPORT_STATUS",
+		"Status de denúncia inválido.",
+		"Invalid report status.")
+	ErrInvalidReportTarget = define(http.StatusBadRequest, "INVALID_REPORT_TARGET",
+		"Tipo de alvo da denúncia inválido.",
+		"Invalid report target type.")
+	ErrReportStatusUnchanged = define(http.StatusConflict, "REPORT_STATUS_UNCHANGED",
+		"A denúncia já está com este status.",
+		"The report already has this status.")
+	ErrInvalidReportAction = define(http.StatusBadRequest, "INVALID_REPORT_ACTION",
+		"Ação inválida para esta denúncia.",
+		"Invalid action for this report.")
+	ErrReportActionNotAllowed = define(http.StatusBadRequest, "REPORT_ACTION_NOT_ALLOWED",
+		"Ações só podem ser aplicadas ao resolver a denúncia.",
+		"Actions can only be applied when resolving the report.")
+	ErrInvalidSuspensionDays = define(http.StatusBadRequest, "INVALID_SUSPENSION_DAYS",
+		"A suspensão deve ser de 1 a {max} dias.",
+		"The suspension must last from 1 to {max} days.")
+	ErrReportTargetNotFound = define(http.StatusNotFound, "REPORT_TARGET_NOT_FOUND",
+		"O alvo da denúncia não existe mais.",
+		"The report target no longer exists.")
+	ErrInvalidAssignee = define(http.StatusBadRequest, "INVALID_ASSIGNEE",
+		"Denúncias só podem ser atribuídas a quem pode resolvê-las.",
+		"Reports can only be assigned to staff who can resolve them.")
+)
+
+// Instituições
+var (
+	ErrInstitutionNotFound = define(http.StatusNotFound, "INSTITUTION_NOT_FOUND",
+		"Instituição não encontrada.",
+		"Institution not found.")
+	ErrInvalidDomainPattern = define(http.StatusBadRequest, "INVALID_DOMAIN_PATTERN",
+		"Domínio inválido: {pattern}. Use um domínio como \"usp.br\" ou \"*.usp.br\" para os subdomínios.",
+		"Invalid domain: {pattern}. Use a domain like \"usp.br\" or \"*.usp.br\" for its subdomains.")
+	ErrDomainPatternTaken = define(http.StatusConflict, "DOMAIN_PATTERN_TAKEN",
+		"O domínio {pattern} já pertence a outra instituição.",
+		"The domain {pattern} already belongs to another institution.")
+	ErrInstitutionInUse = define(http.StatusConflict, "INSTITUTION_IN_USE",
+		"A instituição tem usuários vinculados. Desative-a em vez de excluí-la.",
+		"The institution has linked users. Deactivate it instead of deleting it.")
+)
+
+// Administração
+var (
+	ErrReconcileRunNotFound = define(http.StatusNotFound, "RECONCILE_RUN_NOT_FOUND",
+		"Execução do reconciliador não encontrada.",
+		"Reconcile run not found.")
+	ErrReconcileAlreadyRunning = define(http.StatusConflict, "RECONCILE_ALREADY_RUNNING",
+		"O reconciliador já está em execução.",
+		"The reconciler is already running.")
+)
@@ -0,0 +1,75 @@
+// Package audit records the privileged actions of the admins in the
+// admin_actions table, in the same transaction as the action itself.
+package audit
+
+import (
+	"api/internal/models"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Entry describes an action. Before and After are snapshots of the target,
+// nil when it did not exist before or no longer exists after.
+type Entry struct {
+	Action     string
+	TargetType string
+	TargetID   any
+	Before     any
+	After      any
+}
+
+// Record writes e to the audit log in tx, with the current admin and the
+// request metadata taken from c
+func Record(tx *gorm.DB, c *gin.Context, e Entry) error {
+	before, err := snapshot(e.Before)
+	if err != nil {
+		return err
+	}
+	after, err := snapshot(e.After)
+	if err != nil {
+		return err
+	}
+
+	action := models.AdminAction{
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   fmt.Sprint(e.TargetID),
+		Before:     before,
+		After:      after,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+	}
+	if user, ok := c.Get("currentUser"); ok {
+		admin := user.(models.User)
+		action.ActorID = admin.ID
+		action.ActorName = admin.DisplayName
+	}
+
+	return tx.Create(&action).Error
+}
+
+// snapshot converts a model to the JSON object stored in the log
+func snapshot(v any) (models.JSONMap, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m models.JSONMap
+	if err := json.Unmarshal(b, &m); err != nil {
+		// Not an object: keep the value under "value"
+		var value any
+		if err := json.Unmarshal(b, &value); err != nil {
+			return nil, err
+		}
+		return models.JSONMap{"value": value}, nil
+	}
+	return m, nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"api/internal/storage"
+	"context"
+	"log"
+	"os"
+	"strconv"
+)
+
+var (
+	// Storage is where listing images are kept, selected by STORAGE_BACKEND
+	Storage storage.Storage
+	// LocalStorage is set when STORAGE_BACKEND=local, so the router can serve its files
+	LocalStorage *storage.Local
+)
+
+// getEnv returns the environment variable or fallback if it is empty
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// InitStorage builds the storage backend from the environment:
+//   - s3 (default): AWS S3, bucket S3BUCKET in AWS_REGION
+//   - s3-compatible: any S3 API (MinIO, R2...) at S3_ENDPOINT, with optional
+//     S3_PUBLIC_URL and S3_PATH_STYLE (default true)
+//   - local: files under STORAGE_LOCAL_DIR served by the API at STORAGE_PUBLIC_URL,
+//     presigned uploads signed with STORAGE_SIGNING_KEY
+func InitStorage() {
+	backend := getEnv("STORAGE_BACKEND", "s3")
+
+	var err error
+	switch backend {
+	case "s3":
+		Storage, err = storage.NewS3(context.Background(), storage.S3Options{
+			Region: getEnv("AWS_REGION", "us-east-2"),
+			Bucket: os.Getenv("S3BUCKET"),
+		})
+	case "s3-compatible":
+		pathStyle, parseErr := strconv.ParseBool(getEnv("S3_PATH_STYLE", "true"))
+		if parseErr != nil {
+			log.Fatalf("❌ invalid S3_PATH_STYLE: %v", parseErr)
+		}
+		Storage, err = storage.NewS3(context.Background(), storage.S3Options{
+			Region:        getEnv("AWS_REGION", "us-east-1"),
+			Bucket:        os.Getenv("S3BUCKET"),
+			Endpoint:      os.Getenv("S3_ENDPOINT"),
+			PublicBaseURL: os.Getenv("S3_PUBLIC_URL"),
+			UsePathStyle:  pathStyle,
+		})
+	case "local":
+		LocalStorage, err = storage.NewLocal(
+			getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+			getEnv("STORAGE_PUBLIC_URL", "http://localhost:8080/brechoapi/files"),
+			os.Getenv("STORAGE_SIGNING_KEY"),
+		)
+		Storage = LocalStorage
+	default:
+		log.Fatalf("❌ unknown STORAGE_BACKEND %q", backend)
+	}
+
+	if err != nil {
+		log.Fatalf("❌ unable to initialize %s storage: %v", backend, err)
+	}
+
+	log.Printf("✅ Storage initialized successfully (%s)", backend)
+}
@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"api/internal/apperror"
 	"api/internal/models"
 	database "api/internal/repository"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func GetDashboardStats(c *gin.Context) {
@@ -16,24 +19,24 @@ func GetDashboardStats(c *gin.Context) {
 
 	// Conta o total de usuários
 	if err := database.DB.Model(&models.User{}).Count(&totalUsers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users"})
+		apperror.Abort(c, err)
 		return
 	}
 
 	// Conta os anúncios ativos
 	if err := database.DB.Model(&models.Listing{}).Where("status = ?", models.Available).Count(&activeListings).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count active listings"})
+		apperror.Abort(c, err)
 		return
 	}
 	// Conta os anúncios vendidos
 	if err := database.DB.Model(&models.Listing{}).Where("status = ?", models.Sold).Count(&listingsSold).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count sold listings"})
+		apperror.Abort(c, err)
 		return
 	}
 
 	// Conta as denúncias pendentes
 	if err := database.DB.Model(&models.Report{}).Where("status = ?", "open").Count(&pendingReports).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count reports"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -44,3 +47,49 @@ func GetDashboardStats(c *gin.Context) {
 		"pendingReports": pendingReports,
 	})
 }
+
+// GetAuditLog lists the audit log of the admins, newest first. Filters:
+// actor_id, action, target_type, target_id and the from/to dates (RFC 3339).
+func GetAuditLog(c *gin.Context) {
+	pagination, err := parsePaginationParams(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	query := database.DB.Model(&models.AdminAction{})
+	for _, param := range []string{"actor_id", "action", "target_type", "target_id"} {
+		if v := c.Query(param); v != "" {
+			query = query.Where(param+" = ?", v)
+		}
+	}
+	for param, op := range map[string]string{"from": ">=", "to": "<"} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			apperror.Abort(c, apperror.InvalidParam(param))
+			return
+		}
+		query = query.Where("created_at "+op+" ?", t)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var actions []models.AdminAction
+	if err := query.Order("created_at DESC").
+		Limit(pagination.PageSize).
+		Offset(pagination.Offset).
+		Find(&actions).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	sendPaginatedResponse(c, actions, pagination, total)
+}
@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"api/internal/apperror"
 	"api/internal/config"
+	"api/internal/mail"
 	"api/internal/models"
+	"api/internal/moderation"
 	"api/internal/repository"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
@@ -14,15 +18,13 @@ import (
 func Login(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing token"})
-		c.Abort()
+		apperror.Abort(c, apperror.ErrMissingToken)
 		return
 	}
 
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
-		c.Abort()
+		apperror.Abort(c, apperror.ErrInvalidTokenFormat)
 		return
 	}
 
@@ -32,30 +34,42 @@ func Login(c *gin.Context) {
 	ctx := c.Request.Context()
 	token, err := config.AuthClient.VerifyIDToken(ctx, idToken)
 	if err != nil {
-		c.JSON(401, gin.H{"error": "Unauthorized"})
+		apperror.Abort(c, apperror.ErrInvalidToken.Wrap(err))
 		return
 	}
 
 	// Fetch the full Firebase user profile
 	userRecord, err := config.AuthClient.GetUser(ctx, token.UID)
 	if err != nil {
-		fmt.Println(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load user record"})
+		apperror.Abort(c, apperror.ErrAuthProviderFailure.Wrap(err))
 		return
 	}
 
-	// VALIDATION: Check if the university email is allowed
-	universityName, isAllowed := parseUniversity(userRecord.Email)
-	if !isAllowed {
-		// If email is not allowed, delete the user from Firebase Authentication
-		deleteErr := config.AuthClient.DeleteUser(ctx, token.UID)
-		if deleteErr != nil {
-			fmt.Printf("Error deleting non-institutional user %s from Firebase: %v\n", token.UID, deleteErr)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Falha ao processar login devido a e-mail não institucional. Por favor, tente novamente."})
+	// VALIDATION: Check if the email belongs to an allowed institution
+	institution, err := repository.ResolveInstitution(userRecord.Email)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	if institution == nil {
+		// A new sign-up with a non-institutional email is deleted from
+		// Firebase Authentication; existing users (whose institution was
+		// deactivated) are only refused
+		var existing int64
+		if err := repository.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", token.UID).Count(&existing).Error; err != nil {
+			apperror.Abort(c, err)
 			return
 		}
+		if existing == 0 {
+			deleteErr := config.AuthClient.DeleteUser(ctx, token.UID)
+			if deleteErr != nil {
+				fmt.Printf("Error deleting non-institutional user %s from Firebase: %v\n", token.UID, deleteErr)
+				apperror.Abort(c, apperror.ErrAuthProviderFailure.Wrap(deleteErr))
+				return
+			}
+		}
 
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Para acessar o Sanca Brechó é necessário utilizar um e-mail de uma instituição de ensino superior."})
+		apperror.Abort(c, apperror.ErrEmailNotInstitutional)
 		return
 	}
 
@@ -65,18 +79,31 @@ func Login(c *gin.Context) {
 		WithContext(ctx).
 		Where(models.User{ID: userRecord.UID}).
 		Attrs(models.User{
-			DisplayName: userRecord.DisplayName,
-			Email:       userRecord.Email,
-			PhotoURL:    &userRecord.PhotoURL,
-			University:  universityName, // Use the allowed university name
+			DisplayName:   userRecord.DisplayName,
+			Email:         userRecord.Email,
+			PhotoURL:      &userRecord.PhotoURL,
+			InstitutionID: &institution.ID,
 		}).
 		FirstOrCreate(&user)
 
 	if result.Error != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		apperror.Abort(c, result.Error)
 		return
 	}
 
+	if err := moderation.AccountError(user); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	// First login: send the welcome email
+	if result.RowsAffected == 1 {
+		err := mail.Enqueue(repository.DB.WithContext(ctx), user.Email, mail.TemplateWelcome, models.JSONMap{"name": user.DisplayName})
+		if err != nil {
+			log.Printf("⚠️ failed to queue welcome email for %s: %v", user.ID, err)
+		}
+	}
+
 	// If the record already existed, check for profile changes & save
 	if result.RowsAffected == 0 {
 		changed := false
@@ -90,42 +117,37 @@ func Login(c *gin.Context) {
 				changed = true
 			}
 		}
-		// Also update university if it changed based on the email domain
-		if user.University == nil || (user.University != nil && *user.University != *universityName) {
-			user.University = universityName
+		// Also update the institution if it changed based on the email domain
+		if user.InstitutionID == nil || *user.InstitutionID != institution.ID {
+			user.InstitutionID = &institution.ID
 			changed = true
 		}
 
 		if changed {
 			if err := repository.DB.WithContext(ctx).Save(&user).Error; err != nil {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				apperror.Abort(c, err)
 				return
 			}
 		}
 	}
 
-	// Return the user information
-	c.JSON(http.StatusOK, gin.H{"user": user})
-}
+	// Set by the database from the institution
+	user.University = &institution.Name
 
-func parseUniversity(email string) (*string, bool) {
-	parts := strings.SplitN(email, "@", 2)
-	if len(parts) < 2 {
-		return nil, false
-	}
-	domain := parts[1]
-
-	switch domain {
-	case "usp.br":
-		u := "Universidade de São Paulo"
-		return &u, true
-	case "estudante.ufscar.br":
-		u := "Universidade Federal de São Carlos"
-		return &u, true
-	case "aluno.ifsp.edu.br":
-		u := "Instituto Federal de São Paulo"
-		return &u, true
-	default:
-		return nil, false
+	// The institutional email was confirmed by Firebase: that is enough to be
+	// verified, unless a code is required or an admin revoked the verification
+	if !user.Verified && user.VerificationRevokedAt == nil && userRecord.EmailVerified && !verificationRequiresCode() {
+		db := repository.DB.WithContext(ctx)
+		if err := markVerified(db, user.ID, models.VerifiedByInstitutionalEmail); err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		if err := db.First(&user, "id = ?", user.ID).Error; err != nil {
+			apperror.Abort(c, err)
+			return
+		}
 	}
+
+	// Return the user information
+	c.JSON(http.StatusOK, gin.H{"user": user})
 }
@@ -1,22 +1,38 @@
 package handler
 
 import (
+	"api/internal/apperror"
+	"api/internal/audit"
 	"api/internal/models"
+	"api/internal/moderation"
 	"api/internal/repository"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func CreateCategory(c *gin.Context) {
 	var cat models.Category
 	if err := c.ShouldBindJSON(&cat); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
 
-	if err := repository.DB.Create(&cat).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&cat).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditCategoryCreate,
+			TargetType: models.AuditTargetCategory,
+			TargetID:   cat.ID,
+			After:      cat,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -27,11 +43,7 @@ func GetCategory(c *gin.Context) {
 	id := c.Param("id")
 	var cat models.Category
 	if err := repository.DB.First(&cat, "id = ?", id).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve category"})
-		}
+		abortNotFound(c, err, apperror.ErrCategoryNotFound)
 		return
 	}
 
@@ -41,33 +53,121 @@ func GetCategory(c *gin.Context) {
 func GetCategories(c *gin.Context) {
 	var cats []models.Category
 	if err := repository.DB.Find(&cats).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve categories"})
+		apperror.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, cats)
 }
 
+// GetCategoryTree returns the category hierarchy with active listing counts per node
+func GetCategoryTree(c *gin.Context) {
+	var cats []models.Category
+	if err := repository.DB.Order("name").Find(&cats).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var counts []struct {
+		CategoryID int
+		Count      int64
+	}
+	if err := repository.DB.Model(&models.Listing{}).
+		Select("category_id, COUNT(*) AS count").
+		Where("status IN ?", models.ActiveStatuses).
+		Scopes(moderation.VisibleSellers).
+		Group("category_id").
+		Scan(&counts).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	nodes := make(map[int]*models.CategoryTreeNode, len(cats))
+	for _, cat := range cats {
+		nodes[cat.ID] = &models.CategoryTreeNode{
+			ID:       cat.ID,
+			Name:     cat.Name,
+			Icon:     cat.Icon,
+			ParentID: cat.ParentID,
+			Children: []*models.CategoryTreeNode{},
+		}
+	}
+	for _, count := range counts {
+		if node, ok := nodes[count.CategoryID]; ok {
+			node.ListingCount = count.Count
+		}
+	}
+
+	roots := []*models.CategoryTreeNode{}
+	for _, cat := range cats {
+		node := nodes[cat.ID]
+		if cat.ParentID != nil {
+			if parent, ok := nodes[*cat.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	for _, root := range roots {
+		sumListingCounts(root)
+	}
+
+	c.JSON(http.StatusOK, roots)
+}
+
+// sumListingCounts fills TotalListingCount for the node and its descendants
+func sumListingCounts(node *models.CategoryTreeNode) int64 {
+	node.TotalListingCount = node.ListingCount
+	for _, child := range node.Children {
+		node.TotalListingCount += sumListingCounts(child)
+	}
+	return node.TotalListingCount
+}
+
 func UpdateCategory(c *gin.Context) {
 	id := c.Param("id")
 	var cat models.Category
 	if err := repository.DB.First(&cat, "id = ?", id).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve category"})
-		}
+		abortNotFound(c, err, apperror.ErrCategoryNotFound)
 		return
 	}
 
 	var updates models.Category
 	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
 
-	if err := repository.DB.Model(&cat).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+	// A category cannot be moved under itself or one of its descendants
+	if updates.ParentID != nil {
+		subtree, err := repository.CategorySubtreeIDs(cat.ID)
+		if err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		if slices.Contains(subtree, *updates.ParentID) {
+			apperror.Abort(c, apperror.ErrCategoryCycle)
+			return
+		}
+	}
+
+	before := cat
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&cat).Updates(updates).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditCategoryUpdate,
+			TargetType: models.AuditTargetCategory,
+			TargetID:   cat.ID,
+			Before:     before,
+			After:      cat,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -76,15 +176,29 @@ func UpdateCategory(c *gin.Context) {
 
 func DeleteCategory(c *gin.Context) {
 	id := c.Param("id")
-	result := repository.DB.Delete(&models.Category{}, "id = ?", id)
-
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+	var cat models.Category
+	if err := repository.DB.First(&cat, "id = ?", id).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrCategoryNotFound)
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Category{}, "id = ?", cat.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperror.ErrCategoryNotFound
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditCategoryDelete,
+			TargetType: models.AuditTargetCategory,
+			TargetID:   cat.ID,
+			Before:     cat,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
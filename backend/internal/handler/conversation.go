@@ -0,0 +1,515 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/models"
+	"api/internal/notify"
+	"api/internal/realtime"
+	database "api/internal/repository"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxMessageLength caps the body of a message
+const maxMessageLength = 2000
+
+// conversationQuery preloads the listing and only the public data of the participants
+func conversationQuery() *gorm.DB {
+	return database.DB.
+		Preload("Listing").
+		Preload("Buyer", func(db *gorm.DB) *gorm.DB {
+			return db.Select(publicUserFields)
+		}).
+		Preload("Seller", func(db *gorm.DB) *gorm.DB {
+			return db.Select(publicUserFields)
+		})
+}
+
+// conversationClosed reports whether the listing no longer accepts messages.
+// A nil listing was deleted: its threads stay readable.
+func conversationClosed(listing *models.Listing) bool {
+	return listing == nil || listing.Status == models.Sold || listing.Status == models.Deleted || listing.Status == models.Hidden
+}
+
+// otherParticipant returns the id of the participant that is not userID
+func otherParticipant(conv *models.Conversation, userID string) string {
+	if conv.BuyerID == userID {
+		return conv.SellerID
+	}
+	return conv.BuyerID
+}
+
+// loadConversation returns the conversation if userID takes part in it.
+// Others get ErrConversationNotFound, so thread ids don't leak.
+func loadConversation(id string, userID string) (*models.Conversation, error) {
+	convID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, apperror.ErrConversationNotFound
+	}
+
+	var conv models.Conversation
+	if err := conversationQuery().
+		Where("id = ? AND (buyer_id = ? OR seller_id = ?)", convID, userID, userID).
+		First(&conv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.ErrConversationNotFound
+		}
+		return nil, err
+	}
+
+	blocked, err := database.IsBlocked(userID, otherParticipant(&conv, userID))
+	if err != nil {
+		return nil, err
+	}
+	conv.Blocked = blocked
+	conv.Closed = conversationClosed(conv.Listing)
+
+	return &conv, nil
+}
+
+// parseMessageBody trims and validates the body of a message
+func parseMessageBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", apperror.ErrEmptyMessage
+	}
+	if len([]rune(body)) > maxMessageLength {
+		return "", apperror.ErrMessageTooLong.With("max", maxMessageLength)
+	}
+	return body, nil
+}
+
+// createMessage stores the message and bumps the conversation in the same transaction
+func createMessage(conv *models.Conversation, senderID, body string) (*models.Message, error) {
+	msg := models.Message{
+		ID:             uuid.New(),
+		ConversationID: conv.ID,
+		SenderID:       senderID,
+		Body:           body,
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Sender").Create(&msg).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Conversation{}).
+			Where("id = ?", conv.ID).
+			Update("last_message_at", msg.CreatedAt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conv.LastMessageAt = &msg.CreatedAt
+	return &msg, nil
+}
+
+// StartConversation opens (or returns the existing) thread between the current
+// user, as buyer, and the seller of a listing, optionally with a first message
+func StartConversation(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var request struct {
+		ListingID uuid.UUID `json:"listing_id" binding:"required"`
+		Message   *string   `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	var body string
+	if request.Message != nil {
+		var err error
+		if body, err = parseMessageBody(*request.Message); err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+	}
+
+	var listing models.Listing
+	if err := database.DB.First(&listing, "id = ?", request.ListingID).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrListingNotFound)
+		return
+	}
+
+	if listing.UserID == CurrentUser.ID {
+		apperror.Abort(c, apperror.ErrCannotMessageSelf)
+		return
+	}
+
+	blocked, err := database.IsBlocked(CurrentUser.ID, listing.UserID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	if blocked {
+		apperror.Abort(c, apperror.ErrUserBlocked)
+		return
+	}
+
+	// An existing thread stays readable, but new ones need an open listing
+	var conv models.Conversation
+	err = database.DB.Where("listing_id = ? AND buyer_id = ?", listing.ID, CurrentUser.ID).First(&conv).Error
+	status := http.StatusOK
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if conversationClosed(&listing) {
+			apperror.Abort(c, apperror.ErrListingNotAvailable)
+			return
+		}
+
+		conv = models.Conversation{
+			ID:           uuid.New(),
+			ListingID:    &listing.ID,
+			ListingTitle: listing.Title,
+			BuyerID:      CurrentUser.ID,
+			SellerID:     listing.UserID,
+		}
+		created := false
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			// Two concurrent requests may both get here: the unique index keeps one thread
+			result := tx.Omit("Listing", "Buyer", "Seller").
+				Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&conv)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			created = true
+
+			return notify.Send(tx, listing.UserID, models.NotificationListingInterest, models.JSONMap{
+				"conversation_id": conv.ID,
+				"listing_id":      listing.ID,
+				"listing_title":   listing.Title,
+				"buyer_name":      CurrentUser.DisplayName,
+			})
+		})
+		if err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		if created {
+			status = http.StatusCreated
+		} else if err := database.DB.Where("listing_id = ? AND buyer_id = ?", listing.ID, CurrentUser.ID).First(&conv).Error; err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+	} else if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	loaded, err := loadConversation(conv.ID.String(), CurrentUser.ID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if body != "" {
+		if loaded.Closed {
+			apperror.Abort(c, apperror.ErrConversationClosed)
+			return
+		}
+		msg, err := createMessage(loaded, CurrentUser.ID, body)
+		if err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		loaded.LastMessage = msg
+		realtime.PublishRef(otherParticipant(loaded, CurrentUser.ID), realtime.EventMessageNew, realtime.RefMessage, msg.ID)
+	}
+
+	c.JSON(status, loaded)
+}
+
+// GetConversations lists the threads of the current user, as buyer or seller,
+// most recent activity first, with the last message and unread count of each
+func GetConversations(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	pagination, err := parsePaginationParams(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	mine := database.DB.Where("buyer_id = ? OR seller_id = ?", CurrentUser.ID, CurrentUser.ID)
+
+	var total int64
+	if err := database.DB.Model(&models.Conversation{}).Where(mine).Count(&total).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var conversations []models.Conversation
+	if err := conversationQuery().
+		Where(mine).
+		Order("COALESCE(last_message_at, created_at) DESC, id DESC").
+		Limit(pagination.PageSize).
+		Offset(pagination.Offset).
+		Find(&conversations).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if len(conversations) > 0 {
+		ids := make([]uuid.UUID, len(conversations))
+		others := make([]string, len(conversations))
+		for i, conv := range conversations {
+			ids[i] = conv.ID
+			others[i] = otherParticipant(&conv, CurrentUser.ID)
+		}
+
+		var unread []struct {
+			ConversationID uuid.UUID
+			Count          int64
+		}
+		if err := database.DB.Model(&models.Message{}).
+			Select("conversation_id, COUNT(*) AS count").
+			Where("conversation_id IN ? AND sender_id <> ? AND read_at IS NULL", ids, CurrentUser.ID).
+			Group("conversation_id").
+			Scan(&unread).Error; err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		unreadByConv := make(map[uuid.UUID]int64, len(unread))
+		for _, u := range unread {
+			unreadByConv[u.ConversationID] = u.Count
+		}
+
+		var lastMessages []models.Message
+		if err := database.DB.
+			Raw("SELECT DISTINCT ON (conversation_id) * FROM messages WHERE conversation_id IN ? ORDER BY conversation_id, created_at DESC, id DESC", ids).
+			Scan(&lastMessages).Error; err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		lastByConv := make(map[uuid.UUID]models.Message, len(lastMessages))
+		for _, m := range lastMessages {
+			lastByConv[m.ConversationID] = m
+		}
+
+		var blocks []models.UserBlock
+		if err := database.DB.
+			Where("(blocker_id = ? AND blocked_id IN ?) OR (blocked_id = ? AND blocker_id IN ?)", CurrentUser.ID, others, CurrentUser.ID, others).
+			Find(&blocks).Error; err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		blocked := make(map[string]bool, len(blocks))
+		for _, b := range blocks {
+			blocked[b.BlockerID] = true
+			blocked[b.BlockedID] = true
+		}
+
+		for i := range conversations {
+			conv := &conversations[i]
+			conv.UnreadCount = unreadByConv[conv.ID]
+			if m, ok := lastByConv[conv.ID]; ok {
+				conv.LastMessage = &m
+			}
+			conv.Blocked = blocked[otherParticipant(conv, CurrentUser.ID)]
+			conv.Closed = conversationClosed(conv.Listing)
+		}
+	}
+
+	sendPaginatedResponse(c, conversations, pagination, total)
+}
+
+func GetConversation(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	conv, err := loadConversation(c.Param("id"), CurrentUser.ID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if err := database.DB.Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_id <> ? AND read_at IS NULL", conv.ID, CurrentUser.ID).
+		Count(&conv.UnreadCount).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conv)
+}
+
+// GetConversationMessages returns the messages of a thread, newest first
+func GetConversationMessages(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	conv, err := loadConversation(c.Param("id"), CurrentUser.ID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	pagination, err := parsePaginationParams(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Message{}).Where("conversation_id = ?", conv.ID).Count(&total).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var messages []models.Message
+	if err := database.DB.
+		Where("conversation_id = ?", conv.ID).
+		Order("created_at DESC, id DESC").
+		Limit(pagination.PageSize).
+		Offset(pagination.Offset).
+		Find(&messages).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	sendPaginatedResponse(c, messages, pagination, total)
+}
+
+func SendMessage(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var request struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	body, err := parseMessageBody(request.Body)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	conv, err := loadConversation(c.Param("id"), CurrentUser.ID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	// Vendidos ou removidos continuam legíveis, mas não recebem mensagens
+	if conv.Closed {
+		apperror.Abort(c, apperror.ErrConversationClosed)
+		return
+	}
+	if conv.Blocked {
+		apperror.Abort(c, apperror.ErrUserBlocked)
+		return
+	}
+
+	msg, err := createMessage(conv, CurrentUser.ID, body)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	realtime.PublishRef(otherParticipant(conv, CurrentUser.ID), realtime.EventMessageNew, realtime.RefMessage, msg.ID)
+
+	c.JSON(http.StatusCreated, msg)
+}
+
+// MarkConversationRead marks every message the other participant sent as read
+func MarkConversationRead(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	conv, err := loadConversation(c.Param("id"), CurrentUser.ID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	result := database.DB.Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_id <> ? AND read_at IS NULL", conv.ID, CurrentUser.ID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		apperror.Abort(c, result.Error)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked_read": result.RowsAffected})
+}
+
+// GetBlockedUsers lists the users the current user has blocked
+func GetBlockedUsers(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var blocks []models.UserBlock
+	if err := database.DB.
+		Preload("Blocked", func(db *gorm.DB) *gorm.DB {
+			return db.Select(publicUserFields)
+		}).
+		Where("blocker_id = ?", CurrentUser.ID).
+		Order("created_at DESC").
+		Find(&blocks).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, blocks)
+}
+
+// BlockUser stops all messaging between the current user and the user of the slug
+func BlockUser(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var target models.User
+	if err := database.DB.Where("slug = ?", c.Param("slug")).First(&target).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrUserNotFound)
+		return
+	}
+
+	if target.ID == CurrentUser.ID {
+		apperror.Abort(c, apperror.ErrCannotBlockSelf)
+		return
+	}
+
+	block := models.UserBlock{BlockerID: CurrentUser.ID, BlockedID: target.ID}
+	if err := database.DB.Omit("Blocker", "Blocked").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&block).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User blocked"})
+}
+
+func UnblockUser(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var target models.User
+	if err := database.DB.Where("slug = ?", c.Param("slug")).First(&target).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrUserNotFound)
+		return
+	}
+
+	if err := database.DB.
+		Where("blocker_id = ? AND blocked_id = ?", CurrentUser.ID, target.ID).
+		Delete(&models.UserBlock{}).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
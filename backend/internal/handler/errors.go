@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// abortNotFound reports notFound when err is gorm.ErrRecordNotFound, and err otherwise
+func abortNotFound(c *gin.Context, err error, notFound *apperror.Error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apperror.Abort(c, notFound)
+		return
+	}
+	apperror.Abort(c, err)
+}
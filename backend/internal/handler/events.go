@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"api/internal/models"
+	"api/internal/realtime"
+	"io"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval keeps idle streams alive through proxies
+const heartbeatInterval = 25 * time.Second
+
+// StreamEvents streams the events of the current user as Server-Sent Events.
+// The stream ends when the client disconnects or when its ID token expires
+// (a "token_expired" event is sent), so the client reconnects with a fresh token.
+func StreamEvents(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	events, unsubscribe := realtime.Subscribe(CurrentUser.ID)
+	defer unsubscribe()
+
+	expired := time.After(time.Until(c.GetTime("tokenExpiresAt")))
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // sem buffer no nginx
+
+	c.SSEvent("ready", gin.H{"user_id": CurrentUser.ID})
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-expired:
+			c.SSEvent("token_expired", gin.H{})
+			return false
+		case ev := <-events:
+			c.Render(-1, sse.Event{Id: ev.ID, Event: ev.Type, Data: ev})
+			return true
+		case <-heartbeat.C:
+			io.WriteString(w, ": ping\n\n")
+			return true
+		}
+	})
+}
@@ -1,18 +1,22 @@
 package handler
 
 import (
+	"api/internal/apperror"
 	"api/internal/models"
+	"api/internal/realtime"
 	"api/internal/repository"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func AddFavorite(c *gin.Context) {
 	var fav models.Favorite
 	if err := c.ShouldBindJSON(&fav); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
 
@@ -20,16 +24,30 @@ func AddFavorite(c *gin.Context) {
 
 	// Criar o favorito no banco de dados
 	if err := repository.DB.Create(&fav).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to favorite listing"})
+		switch {
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			apperror.Abort(c, apperror.ErrAlreadyFavorited)
+		case errors.Is(err, gorm.ErrForeignKeyViolated):
+			apperror.Abort(c, apperror.ErrListingNotFound)
+		default:
+			apperror.Abort(c, err)
+		}
 		return
 	}
 
 	// Carregar os dados relacionados (User e Listing) após a criação
 	if err := repository.DB.Preload("User").Preload("Listing").First(&fav, "user_id = ? AND listing_id = ?", fav.UserID, fav.ListingID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load related data"})
+		apperror.Abort(c, err)
 		return
 	}
 
+	if fav.Listing.UserID != fav.UserID {
+		realtime.Publish(fav.Listing.UserID, realtime.EventListingFavorited, gin.H{
+			"listing_id":    fav.Listing.ID,
+			"listing_title": fav.Listing.Title,
+		})
+	}
+
 	c.JSON(http.StatusCreated, fav)
 }
 
@@ -38,7 +56,7 @@ func ListFavorites(c *gin.Context) {
 
 	// Obter todos os favoritos
 	if err := repository.DB.Preload("User").Preload("Listing.User").Preload("Listing.Category").Find(&favorites).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch favorites"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -50,12 +68,12 @@ func RemoveFavorite(c *gin.Context) {
 	listingID := c.Query("listing_id")
 
 	if userID == "" || listingID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id and listing_id required"})
+		apperror.Abort(c, apperror.ErrMissingParam.With("param", "user_id, listing_id"))
 		return
 	}
 
 	if err := repository.DB.Delete(&models.Favorite{}, "user_id = ? AND listing_id = ?", userID, listingID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove favorite"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -67,7 +85,7 @@ func ListFavoritesByUser(c *gin.Context) {
 
 	var favorites []models.Favorite
 	if err := repository.DB.Preload("User").Preload("Listing.User").Preload("Listing.Category").Where("user_id = ?", userID).Find(&favorites).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch favorites"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/audit"
+	"api/internal/models"
+	"api/internal/repository"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type createInstitutionRequest struct {
+	Name      string   `json:"name" binding:"required,max=200"`
+	ShortName string   `json:"short_name" binding:"required,max=50"`
+	Domains   []string `json:"domains" binding:"required,min=1"`
+	Campus    *string  `json:"campus" binding:"omitempty,max=200"`
+	City      *string  `json:"city" binding:"omitempty,max=200"`
+	Active    *bool    `json:"active"`
+}
+
+type updateInstitutionRequest struct {
+	Name      *string  `json:"name" binding:"omitempty,min=1,max=200"`
+	ShortName *string  `json:"short_name" binding:"omitempty,min=1,max=50"`
+	Domains   []string `json:"domains" binding:"omitempty,min=1"`
+	Campus    *string  `json:"campus" binding:"omitempty,max=200"`
+	City      *string  `json:"city" binding:"omitempty,max=200"`
+	Active    *bool    `json:"active"`
+}
+
+// institutionQuery preloads the domains of the institutions
+func institutionQuery() *gorm.DB {
+	return repository.DB.Preload("Domains", func(db *gorm.DB) *gorm.DB {
+		return db.Order("pattern")
+	})
+}
+
+// GetInstitutions lists the institutions whose members can sign up
+func GetInstitutions(c *gin.Context) {
+	var institutions []models.Institution
+	if err := institutionQuery().Where("active").Order("name").Find(&institutions).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, institutions)
+}
+
+// GetInstitutionsAdmin lists every institution, inactive ones included
+func GetInstitutionsAdmin(c *gin.Context) {
+	var institutions []models.Institution
+	if err := institutionQuery().Order("name").Find(&institutions).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, institutions)
+}
+
+func CreateInstitution(c *gin.Context) {
+	var req createInstitutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	domains, err := normalizeDomains(req.Domains)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	institution := models.Institution{
+		Name:      req.Name,
+		ShortName: req.ShortName,
+		Campus:    req.Campus,
+		City:      req.City,
+		Active:    req.Active == nil || *req.Active,
+	}
+
+	err = repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Domains").Create(&institution).Error; err != nil {
+			return err
+		}
+		// Create skips the zero value of Active because of its default
+		if !institution.Active {
+			if err := tx.Model(&institution).Update("active", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := replaceInstitutionDomains(tx, &institution, domains); err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditInstitutionCreate,
+			TargetType: models.AuditTargetInstitution,
+			TargetID:   institution.ID,
+			After:      institution,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, institution)
+}
+
+func UpdateInstitution(c *gin.Context) {
+	var req updateInstitutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	var domains []string
+	if req.Domains != nil {
+		if len(req.Domains) == 0 {
+			apperror.Abort(c, apperror.ErrInvalidBody)
+			return
+		}
+		var err error
+		if domains, err = normalizeDomains(req.Domains); err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+	}
+
+	var institution models.Institution
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Domains").First(&institution, "id = ?", c.Param("id")).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.ErrInstitutionNotFound
+			}
+			return err
+		}
+		before := institution
+
+		updates := map[string]any{}
+		if req.Name != nil {
+			updates["name"] = *req.Name
+		}
+		if req.ShortName != nil {
+			updates["short_name"] = *req.ShortName
+		}
+		if req.Campus != nil {
+			updates["campus"] = *req.Campus
+		}
+		if req.City != nil {
+			updates["city"] = *req.City
+		}
+		if req.Active != nil {
+			updates["active"] = *req.Active
+		}
+		if len(updates) == 0 && domains == nil {
+			return nil
+		}
+
+		if len(updates) > 0 {
+			if err := tx.Model(&institution).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if domains != nil {
+			if err := replaceInstitutionDomains(tx, &institution, domains); err != nil {
+				return err
+			}
+		}
+		if err := tx.Preload("Domains").First(&institution, institution.ID).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditInstitutionUpdate,
+			TargetType: models.AuditTargetInstitution,
+			TargetID:   institution.ID,
+			Before:     before,
+			After:      institution,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, institution)
+}
+
+// DeleteInstitution removes an institution nobody is linked to; the others
+// must be deactivated so their users keep their history
+func DeleteInstitution(c *gin.Context) {
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		var institution models.Institution
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Domains").First(&institution, "id = ?", c.Param("id")).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.ErrInstitutionNotFound
+			}
+			return err
+		}
+
+		var users int64
+		if err := tx.Model(&models.User{}).Where("institution_id = ?", institution.ID).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return apperror.ErrInstitutionInUse
+		}
+
+		if err := tx.Delete(&institution).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditInstitutionDelete,
+			TargetType: models.AuditTargetInstitution,
+			TargetID:   institution.ID,
+			Before:     institution,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Institution deleted"})
+}
+
+// normalizeDomains validates and normalizes domain patterns, dropping repeated ones
+func normalizeDomains(patterns []string) ([]string, error) {
+	domains := []string{}
+	for _, pattern := range patterns {
+		normalized, ok := models.NormalizeDomainPattern(pattern)
+		if !ok {
+			return nil, apperror.ErrInvalidDomainPattern.With("pattern", pattern)
+		}
+		if !slices.Contains(domains, normalized) {
+			domains = append(domains, normalized)
+		}
+	}
+	return domains, nil
+}
+
+// replaceInstitutionDomains sets the domains of the institution. A pattern
+// that belongs to another institution violates the key of institution_domains.
+func replaceInstitutionDomains(tx *gorm.DB, institution *models.Institution, patterns []string) error {
+	if err := tx.Where("institution_id = ?", institution.ID).Delete(&models.InstitutionDomain{}).Error; err != nil {
+		return err
+	}
+
+	institution.Domains = make([]models.InstitutionDomain, 0, len(patterns))
+	for _, pattern := range patterns {
+		domain := models.InstitutionDomain{Pattern: pattern, InstitutionID: institution.ID}
+		if err := tx.Create(&domain).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return apperror.ErrDomainPatternTaken.With("pattern", pattern)
+			}
+			return err
+		}
+		institution.Domains = append(institution.Domains, domain)
+	}
+	return nil
+}
@@ -1,9 +1,14 @@
 package handler
 
 import (
+	"api/internal/apperror"
+	"api/internal/audit"
 	"api/internal/models"
+	"api/internal/moderation"
+	"api/internal/notify"
 	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 
 	database "api/internal/repository"
@@ -11,9 +16,27 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var publicUserFields = "id, display_name, slug, photo_url, university, verified, role, created_at"
+var publicUserFields = "id, display_name, slug, photo_url, university, institution_id, verified, role, created_at"
+
+// editableListingFields are the columns an owner can change with UpdateListing.
+// Reservations, sales and ownership go through their own endpoints.
+var editableListingFields = []string{
+	"title", "description", "price", "condition", "keywords", "category_id",
+	"is_negotiable", "seller_can_deliver", "location", "status",
+}
+
+// maxPageSize caps the `pageSize` param of every listing feed
+const maxPageSize = 100
+
+// Limits of a listing
+const (
+	maxTitleLength       = 100
+	maxDescriptionLength = 1000
+	maxActiveListings    = 20
+)
 
 // paginationParams holds common pagination parameters
 type paginationParams struct {
@@ -23,50 +46,59 @@ type paginationParams struct {
 }
 
 // parsePaginationParams parses and validates pagination query parameters.
-// Returns paginationParams and an error message if validation fails.
-func parsePaginationParams(c *gin.Context) (*paginationParams, string) {
+// Returns paginationParams, or an error if validation fails.
+func parsePaginationParams(c *gin.Context) (*paginationParams, error) {
 	pageStr := c.DefaultQuery("page", "1")
 	pageSizeStr := c.DefaultQuery("pageSize", "20")
 
 	page, err := strconv.Atoi(pageStr)
 	if err != nil || page < 1 {
-		return nil, "invalid `page` param"
+		return nil, apperror.InvalidParam("page")
 	}
 
 	pageSize, err := strconv.Atoi(pageSizeStr)
 	if err != nil || pageSize < 1 {
-		return nil, "invalid `pageSize` param"
+		return nil, apperror.InvalidParam("pageSize")
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
 	}
 
 	return &paginationParams{
 		Page:     page,
 		PageSize: pageSize,
 		Offset:   (page - 1) * pageSize,
-	}, ""
+	}, nil
 }
 
-// parseCategoryParam parses and validates the category query parameter.
-// Returns (categoryID, hasCategory, errorMessage, httpStatus).
-func parseCategoryParam(c *gin.Context) (int, bool, string, int) {
+// parseCategoryParam parses and validates the category query parameter and
+// resolves it to the category plus all of its descendants.
+// Returns (categoryIDs, hasCategory, error).
+func parseCategoryParam(c *gin.Context) ([]int, bool, error) {
 	categoryStr := c.Query("category")
 	if categoryStr == "" {
-		return 0, false, "", 0
+		return nil, false, nil
 	}
 
 	id, err := strconv.Atoi(categoryStr)
 	if err != nil {
-		return 0, false, "invalid `category` param", http.StatusBadRequest
+		return nil, false, apperror.InvalidParam("category")
 	}
 
 	var category models.Category
 	if err := database.DB.First(&category, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return 0, false, "Invalid CategoryID", http.StatusBadRequest
+			return nil, false, apperror.ErrInvalidCategory
 		}
-		return 0, false, "Failed to retrieve category", http.StatusInternalServerError
+		return nil, false, err
+	}
+
+	ids, err := database.CategorySubtreeIDs(id)
+	if err != nil {
+		return nil, false, err
 	}
 
-	return id, true, "", 0
+	return ids, true, nil
 }
 
 // baseListingQuery returns a base query with common preloads for listing queries.
@@ -88,7 +120,19 @@ func sendPaginatedResponse(c *gin.Context, data interface{}, pagination *paginat
 	})
 }
 
-func checkIsAdmin(c *gin.Context) bool {
+// sendFacetedResponse sends a paginated response with facet counts.
+func sendFacetedResponse(c *gin.Context, data interface{}, pagination *paginationParams, total int64, facets *listingFacets) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":     data,
+		"page":     pagination.Page,
+		"pageSize": pagination.PageSize,
+		"total":    total,
+		"facets":   facets,
+	})
+}
+
+// checkCanModerateListings reports whether the current user, if any, sees every listing
+func checkCanModerateListings(c *gin.Context) bool {
 	user, exists := c.Get("currentUser")
 	if !exists {
 		return false
@@ -99,7 +143,7 @@ func checkIsAdmin(c *gin.Context) bool {
 		return false
 	}
 
-	return currentUser.Role == models.RoleAdmin
+	return currentUser.Role.Can(models.PermListingsModerate)
 }
 
 func CreateListing(c *gin.Context) {
@@ -107,23 +151,23 @@ func CreateListing(c *gin.Context) {
 	CurrentUser := user.(models.User)
 
 	if !CurrentUser.Verified {
-		c.JSON(http.StatusForbidden, gin.H{"error": "User not verified"})
+		apperror.Abort(c, apperror.ErrUserNotVerified)
 		return
 	}
 
 	var listing models.Listing
 	if err := c.ShouldBindJSON(&listing); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
 
-	if len(listing.Title) > 100 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Title too long"})
+	if len(listing.Title) > maxTitleLength {
+		apperror.Abort(c, apperror.ErrTitleTooLong.With("max", maxTitleLength))
 		return
 	}
 
-	if len(listing.Description) > 1000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Description too long"})
+	if len(listing.Description) > maxDescriptionLength {
+		apperror.Abort(c, apperror.ErrDescriptionTooLong.With("max", maxDescriptionLength))
 		return
 	}
 
@@ -133,36 +177,36 @@ func CreateListing(c *gin.Context) {
 			return db.Select(publicUserFields)
 		}).
 		Preload("Category").
-		Where("user_id = ? AND status = ?", CurrentUser.ID, models.Available).
+		Where("user_id = ? AND status IN ?", CurrentUser.ID, models.ActiveStatuses).
 		Find(&userActiveListings).Error; err != nil {
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listings"})
+		apperror.Abort(c, err)
 		return
 	}
 
-	if len(userActiveListings) > 20 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot create more than 5 active listings"})
+	if len(userActiveListings) >= maxActiveListings {
+		apperror.Abort(c, apperror.ErrTooManyListings.With("max", maxActiveListings))
 		return
 	}
 
 	//generate UUID for the listing ID
 	listing.ID = uuid.New()
+	listing.UserID = CurrentUser.ID
+	// A new listing is never reserved
+	listing.ReservedForID = nil
+	listing.ReservedUntil = nil
 	//setting the status to available
 	listing.Status = models.Available
 
 	var category models.Category
 	if err := database.DB.First(&category, "id = ?", listing.CategoryID).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CategoryID"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve category"})
-		}
+		abortNotFound(c, err, apperror.ErrInvalidCategory)
 		return
 	}
 
 	// Creating the listing
 	if err := database.DB.Create(&listing).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Listing"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -170,115 +214,165 @@ func CreateListing(c *gin.Context) {
 	if err := database.DB.Preload("User", func(db *gorm.DB) *gorm.DB {
 		return db.Select(publicUserFields)
 	}).Preload("Category").First(&listing, "id = ?", listing.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load related data"})
+		apperror.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, listing)
 }
 
+// GetListings accepts the faceted filters from parseListingFilters and
+// returns the facet counts alongside the page. Passing `cursor` switches to
+// keyset pagination (see parseCursorParam).
 func GetListings(c *gin.Context) {
 	// Parse pagination parameters
-	pagination, errMsg := parsePaginationParams(c)
-	if errMsg != "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+	pagination, err := parsePaginationParams(c)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Parse category parameter
-	categoryID, hasCategory, errMsg, status := parseCategoryParam(c)
-	if errMsg != "" {
-		c.JSON(status, gin.H{"error": errMsg})
+	// Parse category and facet filters
+	filters, err := parseListingFilters(c)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
+	filters.Statuses = models.ActiveStatuses
+	filters.Public = true
 
-	// Count total matching entries
-	var total int64
-	dbCount := database.DB.Model(&models.Listing{}).Where("status = ?", models.Available)
-	if hasCategory {
-		dbCount = dbCount.Where("category_id = ?", categoryID)
+	sort, err := parseSortParam(c, "")
+	if err != nil {
+		apperror.Abort(c, err)
+		return
 	}
-	if err := dbCount.Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count listings"})
+
+	cursor, useCursor, err := parseCursorParam(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	if useCursor && sort != sortRecent {
+		apperror.Abort(c, apperror.ErrCursorRequiresRecentSort)
 		return
 	}
 
-	// Fetch paginated results
-	var listings []models.Listing
-	query := baseListingQuery().Where("status = ?", models.Available)
+	// Keyset pagination for infinite scroll: no COUNT and no OFFSET
+	if useCursor {
+		var listings []models.Listing
+		if err := baseListingQuery().
+			Scopes(filters.scope(facetNone), cursor.scope(), sort.scope("")).
+			Limit(pagination.PageSize + 1).
+			Find(&listings).Error; err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		listings, next := nextListingCursor(listings, pagination.PageSize)
+
+		var facets *listingFacets
+		if cursor == nil {
+			var err error
+			if facets, err = computeListingFacets(filters, ""); err != nil {
+				apperror.Abort(c, err)
+				return
+			}
+		}
 
-	if hasCategory {
-		query = query.Where("category_id = ?", categoryID)
+		sendCursorResponse(c, listings, pagination.PageSize, next, facets)
+		return
 	}
 
-	if err := query.
-		Order("created_at desc, id desc").
+	// Count total matching entries
+	var total int64
+	if err := database.DB.Model(&models.Listing{}).Scopes(filters.scope(facetNone)).Count(&total).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	// Fetch paginated results
+	var listings []models.Listing
+	if err := baseListingQuery().
+		Scopes(filters.scope(facetNone), sort.scope("")).
 		Limit(pagination.PageSize).
 		Offset(pagination.Offset).
 		Find(&listings).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listings"})
+		apperror.Abort(c, err)
 		return
 	}
 
-	sendPaginatedResponse(c, listings, pagination, total)
+	facets, err := computeListingFacets(filters, "")
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	sendFacetedResponse(c, listings, pagination, total, facets)
 }
 
 // query param is mandatory. page and pageSize are optional. page starts at 1.
 // if page and pageSize are not provided, the default is 1 and 20 respectively.
+// Accepts the same faceted filters and `cursor` param as GetListings.
 func GetListingsSearch(c *gin.Context) {
 	q := c.Query("q")
 	if q == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing `q` param"})
+		apperror.Abort(c, apperror.ErrMissingParam.With("param", "q"))
+		return
+	}
+
+	// Parse category and facet filters
+	filters, err := parseListingFilters(c)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
+	if !checkCanModerateListings(c) {
+		filters.Statuses = models.ActiveStatuses
+		filters.Public = true
+	}
 
-	// Parse category parameter
-	categoryID, hasCategory, errMsg, status := parseCategoryParam(c)
-	if errMsg != "" {
-		c.JSON(status, gin.H{"error": errMsg})
+	sort, err := parseSortParam(c, q)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
 	// Parse pagination parameters
-	pagination, errMsg := parsePaginationParams(c)
-	if errMsg != "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+	pagination, err := parsePaginationParams(c)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	likePattern := "%" + q + "%"
-
-	// Count total matching entries with same filters
-	var total int64
-	dbCount := database.DB.Model(&models.Listing{}).Where("(title ILIKE ? OR description ILIKE ?)", likePattern, likePattern)
-	if hasCategory {
-		dbCount = dbCount.Where("category_id = ?", categoryID)
-	}
-	if !checkIsAdmin(c) {
-		dbCount = dbCount.Where("status = ?", models.Available)
+	cursor, useCursor, err := parseCursorParam(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
 	}
-	if err := dbCount.Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count listings"})
+	if useCursor && sort != sortRecent {
+		apperror.Abort(c, apperror.ErrCursorRequiresRecentSort)
 		return
 	}
 
-	// Fetch paginated results with same filters
-	var results []models.Listing
-	query := baseListingQuery().Where("(title ILIKE ? OR description ILIKE ?)", likePattern, likePattern)
-
-	if hasCategory {
-		query = query.Where("category_id = ?", categoryID)
+	// Ranked full-text search with trigram fallback for typos
+	search := database.ListingSearch{
+		Query:   q,
+		Filters: filters.scope(facetNone),
+		Order:   sort.scope(q),
+		Limit:   pagination.PageSize,
+		Offset:  pagination.Offset,
 	}
-	if !checkIsAdmin(c) {
-		query = query.Where("status = ?", models.Available)
+	if useCursor {
+		search.Filters = func(db *gorm.DB) *gorm.DB {
+			return db.Scopes(filters.scope(facetNone), cursor.scope())
+		}
+		search.SkipCount = true
+		search.Limit = pagination.PageSize + 1
+		search.Offset = 0
 	}
 
-	if err := query.
-		Order("created_at desc, id desc").
-		Limit(pagination.PageSize).
-		Offset(pagination.Offset).
-		Find(&results).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listings"})
+	results, total, err := database.SearchListingsFTS(search)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -286,7 +380,28 @@ func GetListingsSearch(c *gin.Context) {
 		results = []models.Listing{}
 	}
 
-	sendPaginatedResponse(c, results, pagination, total)
+	if useCursor {
+		results, next := nextListingCursor(results, pagination.PageSize)
+
+		var facets *listingFacets
+		if cursor == nil {
+			if facets, err = computeListingFacets(filters, q); err != nil {
+				apperror.Abort(c, err)
+				return
+			}
+		}
+
+		sendCursorResponse(c, results, pagination.PageSize, next, facets)
+		return
+	}
+
+	facets, err := computeListingFacets(filters, q)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	sendFacetedResponse(c, results, pagination, total, facets)
 }
 
 func GetListing(c *gin.Context) {
@@ -299,16 +414,17 @@ func GetListing(c *gin.Context) {
 	}).Preload("Category").Where("id = ?", id)
 
 	// Se NÃO for admin, aplica o filtro de status
-	if !checkIsAdmin(c) {
-		query = query.Where("status IN ?", []models.Status{models.Available, models.Sold})
+	if !checkCanModerateListings(c) {
+		query = query.Where("status IN ?", []models.Status{models.Available, models.Reserved, models.Sold}).Scopes(moderation.VisibleSellers)
 	}
 	// Se for admin, o query continua sem filtro de status (vê tudo)
 
 	if err := query.First(&listing).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		abortNotFound(c, err, apperror.ErrListingNotFound)
 		return
 	}
 
+	showReservedFor(c, &listing)
 	c.JSON(http.StatusOK, listing)
 }
 
@@ -322,37 +438,45 @@ func GetListingBySlug(c *gin.Context) {
 	}).Preload("Category").Where("slug = ?", slug)
 
 	// Se NÃO for admin, aplica o filtro de status
-	if !checkIsAdmin(c) {
-		query = query.Where("status IN ?", []models.Status{models.Available, models.Sold})
+	if !checkCanModerateListings(c) {
+		query = query.Where("status IN ?", []models.Status{models.Available, models.Reserved, models.Sold}).Scopes(moderation.VisibleSellers)
 	}
 	// Se for admin, o query continua sem filtro de status (vê tudo)
 
 	if err := query.First(&listing).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		abortNotFound(c, err, apperror.ErrListingNotFound)
 		return
 	}
 
+	showReservedFor(c, &listing)
 	c.JSON(http.StatusOK, listing)
 }
 
 func GetListingsByUser(c *gin.Context) {
 	userSlug := c.Param("user_slug")
 
+	sort, err := parseSortParam(c, "")
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
 	var user models.User
 	if err := database.DB.Where("slug = ?", userSlug).First(&user).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
-		}
+		abortNotFound(c, err, apperror.ErrUserNotFound)
 		return
 	}
 
-	var listings []models.Listing
-	if err := database.DB.Preload("User", func(db *gorm.DB) *gorm.DB {
+	query := database.DB.Preload("User", func(db *gorm.DB) *gorm.DB {
 		return db.Select(publicUserFields)
-	}).Preload("Category").Where("user_id = ? AND status = ?", user.ID, models.Available).Find(&listings).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listings for user"})
+	}).Preload("Category").Where("user_id = ? AND status IN ?", user.ID, models.ActiveStatuses)
+	if !checkCanModerateListings(c) {
+		query = query.Scopes(moderation.VisibleSellers)
+	}
+
+	var listings []models.Listing
+	if err := query.Scopes(sort.scope("")).Find(&listings).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -368,39 +492,64 @@ func UpdateListing(c *gin.Context) {
 	// Search for the existing listing by ID
 	var existing models.Listing
 	if err := database.DB.First(&existing, "id = ?", id).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listing"})
-		}
+		abortNotFound(c, err, apperror.ErrListingNotFound)
 		return
 	}
 
 	// Check if the listing is the logged user's listing
 	if existing.UserID != CurrentUser.ID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Cannot update another user's listing"})
+		apperror.Abort(c, apperror.ErrNotListingOwner)
 		return
 	}
 
 	// Bind JSON to a map[string]interface{} to handle zero values correctly
 	var updatesMap map[string]interface{}
 	if err := c.ShouldBindJSON(&updatesMap); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
 
+	// Updates also resolves keys by field name: only the editable columns get through
+	for field := range updatesMap {
+		if !slices.Contains(editableListingFields, field) {
+			apperror.Abort(c, apperror.ErrFieldNotEditable.With("field", field))
+			return
+		}
+	}
+
+	// An owner can only delete or restore the listing: reservations and sales
+	// go through their endpoints, and only an admin can restore a listing
+	// hidden by the moderation
+	if status, ok := updatesMap["status"]; ok {
+		switch existing.Status {
+		case models.Hidden:
+			apperror.Abort(c, apperror.ErrListingHidden)
+			return
+		case models.Reserved:
+			apperror.Abort(c, apperror.ErrListingReserved)
+			return
+		case models.Sold:
+			apperror.Abort(c, apperror.ErrListingNotAvailable)
+			return
+		}
+		if status != string(models.Available) && status != string(models.Deleted) {
+			apperror.Abort(c, apperror.ErrInvalidListingStatus)
+			return
+		}
+	}
+
 	// Updates the slug if the title is provided
 	if title, ok := updatesMap["title"].(string); ok && title != "" {
-		if len(title) > 100 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Title too long"})
+		if len(title) > maxTitleLength {
+			apperror.Abort(c, apperror.ErrTitleTooLong.With("max", maxTitleLength))
 			return
 		}
 	}
 
 	// Check if the description is below the maximum
 	if description, ok := updatesMap["description"].(string); ok && description != "" {
-		if len(description) > 1000 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Description too long"})
+		if len(description) > maxDescriptionLength {
+			apperror.Abort(c, apperror.ErrDescriptionTooLong.With("max", maxDescriptionLength))
 			return
 		}
 	}
@@ -410,11 +559,7 @@ func UpdateListing(c *gin.Context) {
 		if categoryID != existing.CategoryID {
 			var category models.Category
 			if err := database.DB.First(&category, "id = ?", categoryID).Error; err != nil {
-				if err.Error() == "record not found" {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CategoryID"})
-				} else {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve category"})
-				}
+				abortNotFound(c, err, apperror.ErrInvalidCategory)
 				return
 			}
 		}
@@ -422,7 +567,7 @@ func UpdateListing(c *gin.Context) {
 
 	// Use the existing listing as a base and apply updates
 	if err := database.DB.Model(&existing).Updates(updatesMap).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update listing"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -430,11 +575,12 @@ func UpdateListing(c *gin.Context) {
 	if err := database.DB.Preload("User", func(db *gorm.DB) *gorm.DB {
 		return db.Select(publicUserFields)
 	}).Preload("Category").First(&existing, "id = ?", existing.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load related data"})
+		apperror.Abort(c, err)
 		return
 	}
 
 	// Return the updated listing
+	showReservedFor(c, &existing)
 	c.JSON(http.StatusOK, existing)
 }
 
@@ -447,58 +593,75 @@ func DeleteListing(c *gin.Context) {
 	var listing models.Listing
 	// 1. Busca o anúncio
 	if err := database.DB.First(&listing, "id = ?", id).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listing"})
-		}
+		abortNotFound(c, err, apperror.ErrListingNotFound)
 		return
 	}
 
 	// 2. Verifica se o usuário é o dono
 	if listing.UserID != CurrentUser.ID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Cannot delete another user's listing"})
+		apperror.Abort(c, apperror.ErrNotListingOwner)
+		return
+	}
+
+	// Uma venda pendente precisa ser cancelada antes
+	if listing.Status == models.Reserved {
+		apperror.Abort(c, apperror.ErrListingReserved)
+		return
+	}
+
+	// Anúncios ocultados pela moderação continuam registrados
+	if listing.Status == models.Hidden {
+		apperror.Abort(c, apperror.ErrListingHidden)
 		return
 	}
 
 	// 3. Em vez de deletar, atualiza o status para 'deleted'
 	if err := database.DB.Model(&listing).Update("status", models.Deleted).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete listing"})
+		apperror.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Listing deleted successfully"})
 }
 
+// DeleteListingByAdmin removes a listing from the site. It is only marked as
+// deleted: its sales, reviews and reports keep pointing at it.
 func DeleteListingByAdmin(c *gin.Context) {
-	id := c.Param("id")
+	listingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, apperror.ErrListingNotFound)
+		return
+	}
 
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		var listing models.Listing
-		if err := tx.First(&listing, "id = ?", id).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var before models.Listing
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&before, "id = ?", listingID).Error; err != nil {
 			return err
 		}
 
-		if err := tx.Where("listing_id = ?", id).Delete(&models.ListingImage{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("listing_id = ?", id).Delete(&models.Favorite{}).Error; err != nil {
+		listing, err := moderation.TakeDown(tx, listingID, models.Deleted)
+		if err != nil {
 			return err
 		}
 
-		if err := tx.Delete(&models.Listing{}, "id = ?", id).Error; err != nil {
+		if err := audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditListingDelete,
+			TargetType: models.AuditTargetListing,
+			TargetID:   listing.ID,
+			Before:     before,
+			After:      listing,
+		}); err != nil {
 			return err
 		}
 
-		return nil
+		return notify.Send(tx, listing.UserID, models.NotificationListingRemoved, models.JSONMap{
+			"listing_id":    listing.ID,
+			"listing_title": listing.Title,
+		})
 	})
 
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete listing by admin", "details": err.Error()})
+		abortNotFound(c, err, apperror.ErrListingNotFound)
 		return
 	}
 
@@ -506,24 +669,30 @@ func DeleteListingByAdmin(c *gin.Context) {
 }
 
 func GetListingsAdmin(c *gin.Context) {
-	pagination, errMsg := parsePaginationParams(c)
-	if errMsg != "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+	pagination, err := parsePaginationParams(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	sort, err := parseSortParam(c, "")
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
 	var total int64
 	if err := database.DB.Model(&models.Listing{}).Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count listings"})
+		apperror.Abort(c, err)
 		return
 	}
 	var listings []models.Listing
 	if err := baseListingQuery().
-		Order("created_at desc, id desc").
+		Scopes(sort.scope("")).
 		Limit(pagination.PageSize).
 		Offset(pagination.Offset).
 		Find(&listings).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listings"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -537,21 +706,43 @@ func UpdateListingStatusByAdmin(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	switch input.Status {
+	case models.Available, models.Sold, models.Deleted:
+	default:
+		apperror.Abort(c, apperror.ErrInvalidListingStatus)
 		return
 	}
 
 	var listing models.Listing
 	if err := database.DB.First(&listing, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		abortNotFound(c, err, apperror.ErrListingNotFound)
 		return
 	}
 
-	if err := database.DB.Model(&listing).Update("status", input.Status).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update listing status"})
+	before := listing
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&listing).Update("status", input.Status).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&listing, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditListingStatusUpdate,
+			TargetType: models.AuditTargetListing,
+			TargetID:   listing.ID,
+			Before:     before,
+			After:      listing,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	database.DB.First(&listing, "id = ?", id)
 	c.JSON(http.StatusOK, listing)
 }
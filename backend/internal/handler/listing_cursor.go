@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// listingCursor points at the last listing of a page in the (created_at, id)
+// ordering used by the public feeds. It travels as an opaque base64 token.
+type listingCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// parseCursorParam parses the cursor query parameter.
+// The presence of `cursor` (even empty, for the first page) switches the feed
+// to keyset pagination. Returns (cursor, useCursor, error); cursor is
+// nil on the first page.
+func parseCursorParam(c *gin.Context) (*listingCursor, bool, error) {
+	token, exists := c.GetQuery("cursor")
+	if !exists {
+		return nil, false, nil
+	}
+	if token == "" {
+		return nil, true, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, false, apperror.InvalidParam("cursor")
+	}
+
+	var cursor listingCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == uuid.Nil || cursor.CreatedAt.IsZero() {
+		return nil, false, apperror.InvalidParam("cursor")
+	}
+
+	return &cursor, true, nil
+}
+
+// encode returns the opaque token for the cursor.
+func (cur listingCursor) encode() string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// scope restricts a query ordered by (created_at desc, id desc) to the rows after the cursor.
+func (cur *listingCursor) scope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if cur == nil {
+			return db
+		}
+		return db.Where("(listings.created_at, listings.id) < (?, ?)", cur.CreatedAt, cur.ID)
+	}
+}
+
+// nextListingCursor trims a page fetched with pageSize+1 rows and returns the
+// cursor for the following page, or nil if this is the last one.
+func nextListingCursor(listings []models.Listing, pageSize int) ([]models.Listing, *string) {
+	if len(listings) <= pageSize {
+		return listings, nil
+	}
+
+	listings = listings[:pageSize]
+	last := listings[len(listings)-1]
+	next := listingCursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+	return listings, &next
+}
+
+// sendCursorResponse sends a keyset paginated response. Facets are only
+// computed for the first page, so they are omitted when nil.
+func sendCursorResponse(c *gin.Context, data interface{}, pageSize int, nextCursor *string, facets *listingFacets) {
+	response := gin.H{
+		"data":        data,
+		"pageSize":    pageSize,
+		"next_cursor": nextCursor,
+	}
+	if facets != nil {
+		response["facets"] = facets
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/models"
+	"api/internal/moderation"
+	"strconv"
+	"strings"
+
+	database "api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// listingFilters holds the faceted filters accepted by the listing feeds.
+// Nil pointers and empty slices/strings mean "no filter".
+type listingFilters struct {
+	Statuses    []models.Status
+	Public      bool  // leaves out the listings of banned and suspended users
+	CategoryIDs []int // the requested category and its descendants
+	MinPrice    *float64
+	MaxPrice    *float64
+	Conditions  []models.Condition
+	CanDeliver  *bool
+	Negotiable  *bool
+	Institution *int
+	University  string // the name of an institution, same facet as Institution
+	Location    string
+}
+
+// Facet names, used to skip a facet's own filter when counting its values.
+const (
+	facetNone        = ""
+	facetPrice       = "price"
+	facetCondition   = "condition"
+	facetCanDeliver  = "can_deliver"
+	facetNegotiable  = "negotiable"
+	facetInstitution = "institution"
+	facetLocation    = "location"
+)
+
+type facetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type institutionFacetCount struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type priceRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+type listingFacets struct {
+	Price       priceRange              `json:"price"`
+	Condition   []facetCount            `json:"condition"`
+	CanDeliver  []facetCount            `json:"can_deliver"`
+	Negotiable  []facetCount            `json:"negotiable"`
+	Institution []institutionFacetCount `json:"institution"`
+	University  []facetCount            `json:"university"` // Institution counted by name
+	Location    []facetCount            `json:"location"`
+}
+
+// parseListingFilters parses and validates the faceted filter query parameters.
+// Returns the filters, or an error if validation fails.
+func parseListingFilters(c *gin.Context) (*listingFilters, error) {
+	var filters listingFilters
+
+	categoryIDs, hasCategory, err := parseCategoryParam(c)
+	if err != nil {
+		return nil, err
+	}
+	if hasCategory {
+		filters.CategoryIDs = categoryIDs
+	}
+
+	if v := c.Query("min_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || price < 0 {
+			return nil, apperror.InvalidParam("min_price")
+		}
+		filters.MinPrice = &price
+	}
+
+	if v := c.Query("max_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || price < 0 {
+			return nil, apperror.InvalidParam("max_price")
+		}
+		filters.MaxPrice = &price
+	}
+
+	if filters.MinPrice != nil && filters.MaxPrice != nil && *filters.MinPrice > *filters.MaxPrice {
+		return nil, apperror.ErrInvalidPriceRange
+	}
+
+	// condition can be repeated (?condition=new&condition=used) or comma separated
+	for _, raw := range c.QueryArray("condition") {
+		for _, v := range strings.Split(raw, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			switch models.Condition(v) {
+			case models.New, models.Used, models.Refurbished, models.Broken:
+				filters.Conditions = append(filters.Conditions, models.Condition(v))
+			default:
+				return nil, apperror.InvalidParam("condition")
+			}
+		}
+	}
+
+	if v := c.Query("can_deliver"); v != "" {
+		canDeliver, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, apperror.InvalidParam("can_deliver")
+		}
+		filters.CanDeliver = &canDeliver
+	}
+
+	if v := c.Query("negotiable"); v != "" {
+		negotiable, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, apperror.InvalidParam("negotiable")
+		}
+		filters.Negotiable = &negotiable
+	}
+
+	if v := c.Query("institution"); v != "" {
+		institutionID, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, apperror.InvalidParam("institution")
+		}
+		filters.Institution = &institutionID
+	}
+	filters.University = strings.TrimSpace(c.Query("university"))
+
+	filters.Location = strings.TrimSpace(c.Query("location"))
+
+	return &filters, nil
+}
+
+// scope returns a gorm scope applying every filter except the one of the skipped facet.
+// Columns are qualified so the scope can be combined with joins on users.
+func (f *listingFilters) scope(skip string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(f.Statuses) > 0 {
+			db = db.Where("listings.status IN ?", f.Statuses)
+		}
+		if f.Public {
+			db = moderation.VisibleSellers(db)
+		}
+		if len(f.CategoryIDs) > 0 {
+			db = db.Where("listings.category_id IN ?", f.CategoryIDs)
+		}
+		if skip != facetPrice {
+			if f.MinPrice != nil {
+				db = db.Where("listings.price >= ?", *f.MinPrice)
+			}
+			if f.MaxPrice != nil {
+				db = db.Where("listings.price <= ?", *f.MaxPrice)
+			}
+		}
+		if skip != facetCondition && len(f.Conditions) > 0 {
+			db = db.Where("listings.condition IN ?", f.Conditions)
+		}
+		if skip != facetCanDeliver && f.CanDeliver != nil {
+			db = db.Where("listings.seller_can_deliver = ?", *f.CanDeliver)
+		}
+		if skip != facetNegotiable && f.Negotiable != nil {
+			db = db.Where("listings.is_negotiable = ?", *f.Negotiable)
+		}
+		if skip != facetInstitution && f.Institution != nil {
+			db = db.Where("listings.user_id IN (?)", database.DB.Model(&models.User{}).Select("id").Where("institution_id = ?", *f.Institution))
+		}
+		if skip != facetInstitution && f.University != "" {
+			institutions := database.DB.Model(&models.Institution{}).Select("id").Where("name = ?", f.University)
+			db = db.Where("listings.user_id IN (?)", database.DB.Model(&models.User{}).Select("id").Where("institution_id IN (?)", institutions))
+		}
+		if skip != facetLocation && f.Location != "" {
+			db = db.Where("listings.location = ?", f.Location)
+		}
+		return db
+	}
+}
+
+// computeListingFacets counts the listings matching the filters (and the search
+// query, if any) for every value of each facet. Each facet ignores its own
+// filter so the frontend can show how many results selecting another value gives.
+func computeListingFacets(filters *listingFilters, q string) (*listingFacets, error) {
+	facets := listingFacets{
+		Condition:   []facetCount{},
+		CanDeliver:  []facetCount{},
+		Negotiable:  []facetCount{},
+		Institution: []institutionFacetCount{},
+		University:  []facetCount{},
+		Location:    []facetCount{},
+	}
+
+	run := func(tx *gorm.DB) error {
+		base := func(skip string) *gorm.DB {
+			query := tx.Model(&models.Listing{}).Scopes(filters.scope(skip))
+			if q != "" {
+				query = query.Scopes(database.MatchListings(q))
+			}
+			return query
+		}
+
+		if err := base(facetPrice).
+			Select("COALESCE(MIN(listings.price), 0) AS min, COALESCE(MAX(listings.price), 0) AS max").
+			Scan(&facets.Price).Error; err != nil {
+			return err
+		}
+
+		grouped := []struct {
+			facet  string
+			column string
+			dest   *[]facetCount
+		}{
+			{facetCondition, "listings.condition::text", &facets.Condition},
+			{facetCanDeliver, "listings.seller_can_deliver::text", &facets.CanDeliver},
+			{facetNegotiable, "listings.is_negotiable::text", &facets.Negotiable},
+			{facetLocation, "listings.location", &facets.Location},
+		}
+		for _, g := range grouped {
+			if err := base(g.facet).
+				Select(g.column + " AS value, COUNT(*) AS count").
+				Group(g.column).
+				Order("count DESC, value").
+				Scan(g.dest).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := base(facetInstitution).
+			Joins("JOIN users ON users.id = listings.user_id").
+			Joins("JOIN institutions ON institutions.id = users.institution_id").
+			Select("institutions.id, institutions.name, COUNT(*) AS count").
+			Group("institutions.id, institutions.name").
+			Order("count DESC, institutions.name").
+			Scan(&facets.Institution).Error; err != nil {
+			return err
+		}
+		for _, institution := range facets.Institution {
+			facets.University = append(facets.University, facetCount{Value: institution.Name, Count: institution.Count})
+		}
+		return nil
+	}
+
+	var err error
+	if q != "" {
+		err = database.SearchSession(run)
+	} else {
+		err = run(database.DB)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &facets, nil
+}
@@ -1,126 +1,561 @@
 package handler
 
 import (
+	"api/internal/apperror"
 	"api/internal/config"
+	"api/internal/imaging"
 	"api/internal/models"
 	"api/internal/repository"
+	"api/internal/storage"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"slices"
+	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// maxImagesPerListing caps how many images a listing can have
+const maxImagesPerListing = 6
+
+// maxPendingUploadsPerUser caps presigned keys a user can hold without attaching them
+const maxPendingUploadsPerUser = 20
+
+// pendingUploadTTL is how long a presigned key can still be attached to a listing.
+// It is longer than the presigned URL itself because the key is usually attached
+// only when the listing form is submitted.
+const pendingUploadTTL = 24 * time.Hour
+
+// errTooManyImages is returned when the listing already has maxImagesPerListing images
+var errTooManyImages = apperror.ErrTooManyImages.With("max", maxImagesPerListing)
+
+// lockOwnedListing locks the listing row so concurrent image changes are
+// serialized, and checks it belongs to userID
+func lockOwnedListing(tx *gorm.DB, listingID interface{}, userID string) (*models.Listing, error) {
+	var listing models.Listing
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&listing, "id = ?", listingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.ErrListingNotFound
+		}
+		return nil, err
+	}
+
+	if listing.UserID != userID {
+		return nil, apperror.ErrNotListingOwner
+	}
+
+	return &listing, nil
+}
+
+// countListingImages returns how many images the listing has
+func countListingImages(tx *gorm.DB, listingID uuid.UUID) (int64, error) {
+	var count int64
+	err := tx.Model(&models.ListingImage{}).Where("listing_id = ?", listingID).Count(&count).Error
+	return count, err
+}
+
+// nextImageOrder returns the order after the last image of the listing, which
+// may have gaps after a delete
+func nextImageOrder(tx *gorm.DB, listingID uuid.UUID) (int, error) {
+	var maxOrder int
+	err := tx.Model(&models.ListingImage{}).
+		Where("listing_id = ?", listingID).
+		Select("COALESCE(MAX(\"order\"), -1)").
+		Scan(&maxOrder).Error
+	return maxOrder + 1, err
+}
+
+// checkImageOrder validates an order sent by the client
+func checkImageOrder(order int) error {
+	if order < 0 || order >= maxImagesPerListing {
+		return apperror.ErrInvalidImagePosition.With("max", maxImagesPerListing-1)
+	}
+	return nil
+}
+
+// lockEditableListing is lockOwnedListing for changes to the images: sold,
+// deleted and hidden listings no longer take new ones
+func lockEditableListing(tx *gorm.DB, listingID interface{}, userID string) (*models.Listing, error) {
+	listing, err := lockOwnedListing(tx, listingID, userID)
+	if err != nil {
+		return nil, err
+	}
+	switch listing.Status {
+	case models.Hidden:
+		return nil, apperror.ErrListingHidden
+	case models.Sold, models.Deleted:
+		return nil, apperror.ErrListingNotAvailable
+	}
+	return listing, nil
+}
+
+// readImageUpload reads an upload, refusing it past imaging.MaxUploadSize
+func readImageUpload(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, imaging.MaxUploadSize+1))
+	if err != nil {
+		return nil, apperror.ErrInvalidFile.Wrap(err)
+	}
+	if len(data) > imaging.MaxUploadSize {
+		return nil, apperror.ErrFileTooLarge
+	}
+	return data, nil
+}
+
+// storeImageVariants runs data through imaging.Process and stores a variant
+// per imaging.Sizes under the listing, filling the variants, Src and Key of
+// img. It returns the stored keys, so the caller can remove them if saving
+// img fails.
+func storeImageVariants(ctx context.Context, store storage.Storage, img *models.ListingImage, data []byte) ([]string, error) {
+	variants, err := imaging.Process(data)
+	if err != nil {
+		switch {
+		case errors.Is(err, imaging.ErrUnsupportedType):
+			return nil, apperror.ErrUnsupportedMediaType.With("allowed", strings.Join(imaging.AllowedTypes, ", "))
+		case errors.Is(err, imaging.ErrTooLarge):
+			return nil, apperror.ErrImageTooLarge
+		default:
+			return nil, fmt.Errorf("image processing: %w", err)
+		}
+	}
+
+	uploaded := []string{}
+	for _, v := range variants {
+		key := fmt.Sprintf("listings/%s/%s/%s.jpg", img.ListingID, img.ID, v.Name)
+		if err := store.Put(ctx, key, imaging.ContentType, bytes.NewReader(v.Data)); err != nil {
+			deleteStoredKeys(store, uploaded)
+			return nil, fmt.Errorf("upload: %w", err)
+		}
+		uploaded = append(uploaded, key)
+
+		variant := models.ListingImageVariant{
+			ID:             uuid.New(),
+			ListingImageID: img.ID,
+			Name:           v.Name,
+			Src:            store.PublicURL(key),
+			Key:            key,
+			Width:          v.Width,
+			Height:         v.Height,
+			Size:           int64(len(v.Data)),
+			ContentType:    imaging.ContentType,
+		}
+		img.Variants = append(img.Variants, variant)
+
+		if v.Name == "full" {
+			img.Src = variant.Src
+			img.Key = variant.Key
+		}
+	}
+
+	return uploaded, nil
+}
+
+// deleteStoredKeys removes objects nothing references anymore. Failures are
+// only logged: the storage reconciler removes whatever is left behind.
+func deleteStoredKeys(store storage.Storage, keys []string) {
+	for key, err := range store.DeleteMany(context.Background(), keys) {
+		log.Printf("⚠️ failed to delete %s from the storage: %v", key, err)
+	}
+}
+
 // Handler: returns a presigned URL for uploading a single object
 type PresignRequest struct {
-	Filename    string `json:"filename"`    // e.g. "avatar.png" or "images/2025/06/04/foo.jpg"
-	ContentType string `json:"contentType"` // e.g. "image/png"
+	Filename    string     `json:"filename"`    // e.g. "avatar.png" or "images/2025/06/04/foo.jpg"
+	ContentType string     `json:"contentType"` // e.g. "image/png"
+	ListingID   *uuid.UUID `json:"listing_id"`  // optional: the listing the image will be attached to
 }
 
 type PresignResponse struct {
 	URL       string `json:"url"`       // the presigned PUT URL
 	PublicURL string `json:"publicURL"` // how your app will reference this object (e.g. https://bucket.s3.amazonaws.com/key)
-	Key       string `json:"key"`       // the S3 key you asked for
+	Key       string `json:"key"`       // the storage key you asked for
 }
 
 func GeneratePresignedURL(c *gin.Context) {
 	var req PresignRequest
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON"})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
 
 	// Validate the Content-Type
 	allowed := []string{"image/png", "image/jpeg", "image/jpg"}
 	if req.ContentType == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Content-Type header is required"})
+		apperror.Abort(c, apperror.ErrMissingParam.With("param", "contentType"))
 		return
 	}
 
 	valid := slices.Contains(allowed, req.ContentType)
 	if !valid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Content-Type must be one of: %v", allowed)})
+		apperror.Abort(c, apperror.ErrUnsupportedMediaType.With("allowed", strings.Join(allowed, ", ")))
 		return
 	}
 
-	// Start building the presigned URL request
-	// S3 Key will be a UUID, which is a unique identifier for the object
-	key := uuid.New().String()
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	// When the listing already exists, check it up front so the key can only be attached to it
+	if req.ListingID != nil {
+		err := repository.DB.Transaction(func(tx *gorm.DB) error {
+			if _, err := lockEditableListing(tx, req.ListingID, CurrentUser.ID); err != nil {
+				return err
+			}
+			count, err := countListingImages(tx, *req.ListingID)
+			if err != nil {
+				return err
+			}
+			if count >= maxImagesPerListing {
+				return errTooManyImages
+			}
+			return nil
+		})
+		if err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+	}
 
-	// Build the PutObjectInput
-	input := &s3.PutObjectInput{
-		Bucket:      aws.String(config.BucketName),
-		Key:         aws.String(key),
-		ContentType: aws.String(req.ContentType),
+	var pendingCount int64
+	if err := repository.DB.Model(&models.PendingUpload{}).
+		Where("user_id = ? AND expires_at > ?", CurrentUser.ID, time.Now()).
+		Count(&pendingCount).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	if pendingCount >= maxPendingUploadsPerUser {
+		apperror.Abort(c, apperror.ErrTooManyPendingUploads)
+		return
 	}
 
-	// Ask the presignClient to generate a presigned URL for 15 minutes
-	presignedReq, err := config.PresignClient.PresignPutObject(context.TODO(), input,
-		s3.WithPresignExpires(15*time.Minute),
-	)
+	// Start building the presigned URL request
+	// Storage key will be a UUID, which is a unique identifier for the object
+	key := uuid.New().String()
 
+	// Ask the storage to generate a presigned URL for 15 minutes
+	presignedURL, err := config.Storage.PresignPut(c.Request.Context(), key, req.ContentType, 15*time.Minute)
 	if err != nil {
-		log.Printf("presign error: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate presigned URL"})
+		apperror.Abort(c, fmt.Errorf("presign: %w", err))
+		return
+	}
+
+	// Record the key so only this user can attach it later
+	pending := models.PendingUpload{
+		Key:         key,
+		UserID:      CurrentUser.ID,
+		ListingID:   req.ListingID,
+		ContentType: req.ContentType,
+		ExpiresAt:   time.Now().Add(pendingUploadTTL),
+	}
+	if err := repository.DB.Create(&pending).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
 	// Build the public URL
-	publicURL := fmt.Sprintf("https://%s.s3.amazonaws.com/%s", config.BucketName, key)
+	publicURL := config.Storage.PublicURL(key)
 
 	c.JSON(http.StatusOK, PresignResponse{
-		URL:       presignedReq.URL,
+		URL:       presignedURL,
 		PublicURL: publicURL,
 		Key:       key,
 	})
 }
 
+// UploadListingImage receives the image file itself (multipart field "file"),
+// validates it by its bytes, strips its metadata and stores a re-encoded
+// variant per imaging.Sizes. The image is appended after the listing's current images.
+func UploadListingImage(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	listingID := c.Param("id")
+
+	// Check ownership and room for one more image before processing the file
+	var listing *models.Listing
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if listing, err = lockEditableListing(tx, listingID, CurrentUser.ID); err != nil {
+			return err
+		}
+		count, err := countListingImages(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if count >= maxImagesPerListing {
+			return errTooManyImages
+		}
+		return nil
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, imaging.MaxUploadSize+(1<<20))
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apperror.Abort(c, apperror.ErrFileTooLarge)
+			return
+		}
+		apperror.Abort(c, apperror.ErrInvalidFile.Wrap(err))
+		return
+	}
+	if fileHeader.Size > imaging.MaxUploadSize {
+		apperror.Abort(c, apperror.ErrFileTooLarge)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		apperror.Abort(c, apperror.ErrInvalidFile.Wrap(err))
+		return
+	}
+	defer file.Close()
+
+	data, err := readImageUpload(file)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	img := models.ListingImage{
+		ID:        uuid.New(),
+		ListingID: listing.ID,
+	}
+
+	// Upload every variant before touching the database
+	uploaded, err := storeImageVariants(c.Request.Context(), config.Storage, &img, data)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	err = repository.DB.Transaction(func(tx *gorm.DB) error {
+		// Re-check under the lock, other uploads may have finished meanwhile
+		if _, err := lockEditableListing(tx, listing.ID, CurrentUser.ID); err != nil {
+			return err
+		}
+		count, err := countListingImages(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if count >= maxImagesPerListing {
+			return errTooManyImages
+		}
+
+		if img.Order, err = nextImageOrder(tx, listing.ID); err != nil {
+			return err
+		}
+
+		return tx.Omit("Listing").Create(&img).Error
+	})
+	if err != nil {
+		deleteStoredKeys(config.Storage, uploaded)
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, img)
+}
+
+// CreateListingImage attaches an object uploaded through GeneratePresignedURL
+// to a listing. Only the uploader can attach the key, only to a listing they
+// own. The object goes through the same pipeline as UploadListingImage: its
+// variants are stored and the raw upload is deleted.
 func CreateListingImage(c *gin.Context) {
-	var img models.ListingImage
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
 
-	// Bind JSON para o objeto ListingImage
-	if err := c.ShouldBindJSON(&img); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request struct {
+		ListingID uuid.UUID `json:"listing_id" binding:"required"`
+		Key       string    `json:"key" binding:"required"`
+		Order     *int      `json:"order"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
+	if request.Order != nil {
+		if err := checkImageOrder(*request.Order); err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+	}
 
-	// Gerar UUID para o ID
-	img.ID = uuid.New()
+	// findPending returns the pending upload of the key, if the user can still attach it
+	findPending := func(tx *gorm.DB) (*models.PendingUpload, error) {
+		var pending models.PendingUpload
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ? AND user_id = ? AND expires_at > ?", request.Key, CurrentUser.ID, time.Now()).
+			Where("listing_id IS NULL OR listing_id = ?", request.ListingID).
+			First(&pending).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, apperror.ErrUploadNotFound
+			}
+			return nil, err
+		}
+		return &pending, nil
+	}
 
-	// Validar se o ListingID existe
-	var listing models.Listing
-	if err := repository.DB.First(&listing, "id = ?", img.ListingID).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ListingID"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listing"})
+	// checkRoom checks the listing and the key before processing the object
+	checkRoom := func(tx *gorm.DB) error {
+		if _, err := lockEditableListing(tx, request.ListingID, CurrentUser.ID); err != nil {
+			return err
+		}
+		if _, err := findPending(tx); err != nil {
+			return err
+		}
+		count, err := countListingImages(tx, request.ListingID)
+		if err != nil {
+			return err
+		}
+		if count >= maxImagesPerListing {
+			return errTooManyImages
+		}
+		return nil
+	}
+
+	err := repository.DB.Transaction(checkRoom)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	object, err := config.Storage.Get(ctx, request.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			apperror.Abort(c, apperror.ErrUploadNotFound)
+			return
 		}
+		apperror.Abort(c, fmt.Errorf("download: %w", err))
 		return
 	}
+	data, err := readImageUpload(object)
+	object.Close()
 
-	// Criar o ListingImage no banco de dados
-	if err := repository.DB.Create(&img).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create image"})
+	img := models.ListingImage{
+		ID:        uuid.New(),
+		ListingID: request.ListingID,
+	}
+	var uploaded []string
+	if err == nil {
+		uploaded, err = storeImageVariants(ctx, config.Storage, &img, data)
+	}
+	if err != nil {
+		// The upload is not a usable image: discard it along with its key
+		if errors.Is(err, apperror.ErrFileTooLarge) || errors.Is(err, apperror.ErrUnsupportedMediaType) || errors.Is(err, apperror.ErrImageTooLarge) {
+			repository.DB.Where("key = ?", request.Key).Delete(&models.PendingUpload{})
+			deleteStoredKeys(config.Storage, []string{request.Key})
+		}
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Carregar os dados relacionados (Listing, User e Category) após a criação
+	err = repository.DB.Transaction(func(tx *gorm.DB) error {
+		// Re-check under the lock, the key may have been attached meanwhile
+		err := checkRoom(tx)
+		if err != nil {
+			return err
+		}
+
+		if request.Order != nil {
+			img.Order = *request.Order
+		} else if img.Order, err = nextImageOrder(tx, request.ListingID); err != nil {
+			return err
+		}
+
+		if err := tx.Omit("Listing").Create(&img).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("key = ?", request.Key).Delete(&models.PendingUpload{}).Error
+	})
+	if err != nil {
+		deleteStoredKeys(config.Storage, uploaded)
+		apperror.Abort(c, err)
+		return
+	}
+
+	deleteStoredKeys(config.Storage, []string{request.Key})
+
 	// Retornar o objeto criado
 	c.JSON(http.StatusCreated, img)
 }
 
+// ReorderListingImages sets the order of all images of a listing at once.
+// The body must list every image of the listing exactly once.
+func ReorderListingImages(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	listingID := c.Param("id")
+
+	var request struct {
+		ImageIDs []uuid.UUID `json:"image_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	var images []models.ListingImage
+
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		listing, err := lockOwnedListing(tx, listingID, CurrentUser.ID)
+		if err != nil {
+			return err
+		}
+
+		var current []uuid.UUID
+		if err := tx.Model(&models.ListingImage{}).Where("listing_id = ?", listing.ID).Pluck("id", &current).Error; err != nil {
+			return err
+		}
+
+		// Same set of ids, no duplicates
+		seen := make(map[uuid.UUID]bool, len(request.ImageIDs))
+		for _, id := range request.ImageIDs {
+			if seen[id] || !slices.Contains(current, id) {
+				return apperror.ErrInvalidImageOrder
+			}
+			seen[id] = true
+		}
+		if len(seen) != len(current) {
+			return apperror.ErrInvalidImageOrder
+		}
+
+		for i, id := range request.ImageIDs {
+			if err := tx.Model(&models.ListingImage{}).Where("id = ?", id).Update("order", i).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Preload("Variants").Where("listing_id = ?", listing.ID).Order("\"order\" asc").Find(&images).Error
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, images)
+}
+
 func GetListingImage(c *gin.Context) {
 	id := c.Param("id")
 	var img models.ListingImage
 
-	if err := repository.DB.First(&img, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+	if err := repository.DB.Preload("Variants").First(&img, "id = ?", id).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrImageNotFound)
 		return
 	}
 
@@ -131,8 +566,8 @@ func GetListingImagesByListing(c *gin.Context) {
 	listingID := c.Param("listingID")
 	var images []models.ListingImage
 
-	if err := repository.DB.Where("listing_id = ?", listingID).Order("\"order\" asc").Find(&images).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve images"})
+	if err := repository.DB.Preload("Variants").Where("listing_id = ?", listingID).Order("\"order\" asc").Find(&images).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -143,7 +578,7 @@ func GetListingImages(c *gin.Context) {
 	var images []models.ListingImage
 
 	if err := repository.DB.Find(&images).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve images"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -159,34 +594,37 @@ func UpdateListingImage(c *gin.Context) {
 
 	// Buscar a imagem existente
 	if err := repository.DB.First(&existing, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		abortNotFound(c, err, apperror.ErrImageNotFound)
 		return
 	}
 
 	// Check if the listing is the user's listing
 	var listing models.Listing
 	if err := repository.DB.First(&listing, "id = ?", existing.ListingID).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listing from listing image"})
-		}
+		abortNotFound(c, err, apperror.ErrListingNotFound)
 		return
 	}
 
 	if listing.UserID != CurrentUser.ID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Cannot update another user's listing"})
+		apperror.Abort(c, apperror.ErrNotListingOwner)
 		return
 	}
 
-	var updates map[string]any
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Only the order can change: the key, source and listing are fixed once attached
+	var request struct {
+		Order *int `json:"order" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+	if err := checkImageOrder(*request.Order); err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	if err := repository.DB.Model(&existing).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update image"})
+	if err := repository.DB.Model(&existing).Update("order", *request.Order).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -201,35 +639,36 @@ func DeleteListingImage(c *gin.Context) {
 
 	// Get the listing ID from the listing image id
 	var listingImage models.ListingImage
-	if err := repository.DB.First(&listingImage, "id = ?", id).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listing image"})
-		}
+	if err := repository.DB.Preload("Variants").First(&listingImage, "id = ?", id).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrImageNotFound)
 		return
 	}
 
 	// Check if the listing is the user's listing
 	var listing models.Listing
 	if err := repository.DB.First(&listing, "id = ?", listingImage.ListingID).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listing from image"})
-		}
+		abortNotFound(c, err, apperror.ErrListingNotFound)
 		return
 	}
 
 	if listing.UserID != CurrentUser.ID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Cannot delete another user's listing"})
+		apperror.Abort(c, apperror.ErrNotListingOwner)
 		return
 	}
 
 	if err := repository.DB.Delete(&listingImage).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete image"})
+		apperror.Abort(c, err)
 		return
 	}
 
+	// The variants went with the row, their files go now
+	keys := []string{listingImage.Key}
+	for _, v := range listingImage.Variants {
+		if v.Key != listingImage.Key {
+			keys = append(keys, v.Key)
+		}
+	}
+	deleteStoredKeys(config.Storage, keys)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Image deleted"})
 }
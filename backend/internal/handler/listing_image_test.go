@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/imaging"
+	"api/internal/models"
+	"api/internal/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newLocalStorage(t *testing.T) *storage.Local {
+	t.Helper()
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost/files", "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestStoreImageVariants(t *testing.T) {
+	store := newLocalStorage(t)
+	img := models.ListingImage{ID: uuid.New(), ListingID: uuid.New()}
+
+	keys, err := storeImageVariants(context.Background(), store, &img, testPNG(t, 1000, 500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != len(imaging.Sizes) || len(img.Variants) != len(imaging.Sizes) {
+		t.Fatalf("stored %d keys and %d variants, want %d", len(keys), len(img.Variants), len(imaging.Sizes))
+	}
+
+	for i, v := range img.Variants {
+		want := fmt.Sprintf("listings/%s/%s/%s.jpg", img.ListingID, img.ID, imaging.Sizes[i].Name)
+		if v.Key != want || keys[i] != want {
+			t.Errorf("variant %d stored as %q, want %q", i, v.Key, want)
+		}
+		if v.Src != store.PublicURL(want) {
+			t.Errorf("variant %d has src %q", i, v.Src)
+		}
+
+		object, err := store.Get(context.Background(), v.Key)
+		if err != nil {
+			t.Fatalf("variant %s was not stored: %v", v.Name, err)
+		}
+		data, _ := io.ReadAll(object)
+		object.Close()
+		if int64(len(data)) != v.Size || imaging.DetectContentType(data) != imaging.ContentType {
+			t.Errorf("variant %s stored %d bytes of %s", v.Name, len(data), imaging.DetectContentType(data))
+		}
+	}
+
+	if img.Key != keys[len(keys)-1] || img.Src != store.PublicURL(img.Key) {
+		t.Errorf("image points at %q, want the full variant", img.Key)
+	}
+}
+
+func TestStoreImageVariantsRejectsInvalidFiles(t *testing.T) {
+	store := newLocalStorage(t)
+	img := models.ListingImage{ID: uuid.New(), ListingID: uuid.New()}
+
+	_, err := storeImageVariants(context.Background(), store, &img, []byte("<html>not an image</html>"))
+	if !errors.Is(err, apperror.ErrUnsupportedMediaType) {
+		t.Fatalf("error = %v, want %v", err, apperror.ErrUnsupportedMediaType)
+	}
+
+	objects, err := store.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 0 {
+		t.Errorf("stored %d objects for an invalid file", len(objects))
+	}
+}
+
+func TestReadImageUploadSizeLimit(t *testing.T) {
+	data, err := readImageUpload(bytes.NewReader(make([]byte, imaging.MaxUploadSize)))
+	if err != nil || len(data) != imaging.MaxUploadSize {
+		t.Fatalf("upload at the limit: %d bytes, error %v", len(data), err)
+	}
+
+	_, err = readImageUpload(bytes.NewReader(make([]byte, imaging.MaxUploadSize+1)))
+	if !errors.Is(err, apperror.ErrFileTooLarge) {
+		t.Fatalf("error = %v, want %v", err, apperror.ErrFileTooLarge)
+	}
+}
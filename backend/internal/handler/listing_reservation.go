@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/models"
+	"api/internal/sales"
+	"errors"
+	"net/http"
+	"time"
+
+	database "api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockOwnListing loads and locks a listing of the current user in tx
+func lockOwnListing(tx *gorm.DB, id, userID string) (*models.Listing, error) {
+	var listing models.Listing
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&listing, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.ErrListingNotFound
+		}
+		return nil, err
+	}
+	if listing.UserID != userID {
+		return nil, apperror.ErrNotListingOwner
+	}
+	return &listing, nil
+}
+
+// ReserveListing puts a listing of the current user on hold, optionally for
+// a buyer (by email or slug), for `hours` (48 by default). A reserved listing
+// stays in the feeds and can only be sold to that buyer. Reserving it again
+// changes the buyer and the deadline.
+func ReserveListing(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var request struct {
+		BuyerIdentifier string `json:"buyer_identifier"`
+		Hours           *int   `json:"hours"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	duration := sales.DefaultReservation
+	if request.Hours != nil {
+		duration = time.Duration(*request.Hours) * time.Hour
+		if duration < time.Hour || duration > sales.MaxReservation {
+			apperror.Abort(c, apperror.ErrInvalidReservationDuration.With("max", int(sales.MaxReservation.Hours())))
+			return
+		}
+	}
+
+	var listing *models.Listing
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if listing, err = lockOwnListing(tx, c.Param("id"), CurrentUser.ID); err != nil {
+			return err
+		}
+
+		switch listing.Status {
+		case models.Available:
+		case models.Reserved:
+			// A sale waiting for the buyer holds the listing until it is answered
+			pending, err := sales.HasPending(tx, listing.ID)
+			if err != nil {
+				return err
+			}
+			if pending {
+				return apperror.ErrListingReserved
+			}
+		default:
+			return apperror.ErrListingNotAvailable
+		}
+
+		var reservedFor *string
+		if request.BuyerIdentifier != "" {
+			buyer, err := findBuyer(tx, request.BuyerIdentifier, listing.UserID)
+			if err != nil {
+				return err
+			}
+			reservedFor = &buyer.ID
+		}
+
+		until := time.Now().Add(duration)
+		listing.Status = models.Reserved
+		listing.ReservedForID = reservedFor
+		listing.ReservedUntil = &until
+
+		return tx.Model(listing).Select("status", "reserved_for_id", "reserved_until").Updates(listing).Error
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	showReservedFor(c, listing)
+	c.JSON(http.StatusOK, listing)
+}
+
+// ReleaseListing ends the reservation of a listing of the current user. A
+// reservation held by a pending sale ends by cancelling the sale instead.
+func ReleaseListing(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var listing *models.Listing
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if listing, err = lockOwnListing(tx, c.Param("id"), CurrentUser.ID); err != nil {
+			return err
+		}
+		if listing.Status != models.Reserved {
+			return apperror.ErrNotReserved
+		}
+
+		pending, err := sales.HasPending(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return apperror.ErrListingReserved
+		}
+
+		if err := sales.Release(tx, listing.ID); err != nil {
+			return err
+		}
+		listing.Status = models.Available
+		listing.ReservedForID = nil
+		listing.ReservedUntil = nil
+		return nil
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listing)
+}
+
+// showReservedFor exposes the buyer a listing is held for to the seller and that buyer only
+func showReservedFor(c *gin.Context, listing *models.Listing) {
+	user, exists := c.Get("currentUser")
+	if !exists {
+		return
+	}
+	currentUser, ok := user.(models.User)
+	if !ok {
+		return
+	}
+
+	if currentUser.ID == listing.UserID || (listing.ReservedForID != nil && currentUser.ID == *listing.ReservedForID) {
+		listing.ReservedFor = listing.ReservedForID
+	}
+}
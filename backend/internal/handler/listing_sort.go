@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	database "api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// listingSort is a validated value of the `sort` query param.
+type listingSort string
+
+const (
+	sortRecent    listingSort = "recent"    // newest first (default)
+	sortUpdated   listingSort = "updated"   // recently updated first
+	sortPriceAsc  listingSort = "price_asc" // cheapest first
+	sortPriceDesc listingSort = "price_desc"
+	sortPopular   listingSort = "popular"   // most favorited first
+	sortRelevance listingSort = "relevance" // best FTS match first, only with `q`
+)
+
+// parseSortParam parses and validates the sort query parameter.
+// query is the search term, if any: relevance is the default when it is present
+// and is rejected when it is not. Returns the sort or an error.
+func parseSortParam(c *gin.Context, query string) (listingSort, error) {
+	value := listingSort(c.Query("sort"))
+	if value == "" {
+		if query != "" {
+			return sortRelevance, nil
+		}
+		return sortRecent, nil
+	}
+
+	switch value {
+	case sortRecent, sortUpdated, sortPriceAsc, sortPriceDesc, sortPopular:
+		return value, nil
+	case sortRelevance:
+		if query == "" {
+			return "", apperror.ErrSortRequiresQuery
+		}
+		return value, nil
+	default:
+		return "", apperror.InvalidParam("sort")
+	}
+}
+
+// scope returns a gorm scope ordering a listings query. Every order ends with
+// (created_at, id) so pages are stable. query is only used for relevance.
+func (s listingSort) scope(query string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch s {
+		case sortUpdated:
+			return db.Order("listings.updated_at desc, listings.created_at desc, listings.id desc")
+		case sortPriceAsc:
+			return db.Order("listings.price asc, listings.created_at desc, listings.id desc")
+		case sortPriceDesc:
+			return db.Order("listings.price desc, listings.created_at desc, listings.id desc")
+		case sortPopular:
+			return db.Order("(SELECT COUNT(*) FROM favorites WHERE favorites.listing_id = listings.id) desc, listings.created_at desc, listings.id desc")
+		case sortRelevance:
+			return db.Scopes(database.OrderByRelevance(query))
+		default:
+			return db.Order("listings.created_at desc, listings.id desc")
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/models"
+	"api/internal/notify"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	database "api/internal/repository"
+)
+
+// GetNotifications lists the notifications of the current user, newest first.
+// With ?unread=true only the unread ones are listed.
+func GetNotifications(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	pagination, err := parsePaginationParams(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ?", CurrentUser.ID)
+	switch c.Query("unread") {
+	case "":
+	case "true":
+		query = query.Where("read_at IS NULL")
+	default:
+		apperror.Abort(c, apperror.InvalidParam("unread"))
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var notifications []models.Notification
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(pagination.PageSize).
+		Offset(pagination.Offset).
+		Find(&notifications).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	sendPaginatedResponse(c, notifications, pagination, total)
+}
+
+// MarkNotificationRead marks one notification of the current user as read
+func MarkNotificationRead(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidParam("id"))
+		return
+	}
+
+	var notification models.Notification
+	if err := database.DB.First(&notification, "id = ? AND user_id = ?", id, CurrentUser.ID).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrNotificationNotFound)
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := database.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead marks every notification of the current user as read
+func MarkAllNotificationsRead(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	result := database.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", CurrentUser.ID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		apperror.Abort(c, result.Error)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked_read": result.RowsAffected})
+}
+
+// GetNotificationPreferences lists the preference of the current user for every type and channel
+func GetNotificationPreferences(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	prefs, err := notify.Preferences(database.DB, CurrentUser.ID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateNotificationPreferences opts the current user in or out of the given
+// types and channels. Pairs that are not sent keep their current value.
+func UpdateNotificationPreferences(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var request struct {
+		Preferences []struct {
+			Type    models.NotificationType    `json:"type" binding:"required"`
+			Channel models.NotificationChannel `json:"channel" binding:"required"`
+			Enabled *bool                      `json:"enabled" binding:"required"`
+		} `json:"preferences" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	// A pair sent twice keeps the last value: ON CONFLICT cannot touch a row twice
+	prefs := make([]models.NotificationPreference, 0, len(request.Preferences))
+	index := make(map[string]int, len(request.Preferences))
+	for _, p := range request.Preferences {
+		if !p.Type.Valid() {
+			apperror.Abort(c, apperror.ErrInvalidNotificationType.With("type", p.Type))
+			return
+		}
+		if !p.Channel.Valid() {
+			apperror.Abort(c, apperror.ErrInvalidNotificationChannel.With("channel", p.Channel))
+			return
+		}
+		pref := models.NotificationPreference{
+			UserID:  CurrentUser.ID,
+			Type:    p.Type,
+			Channel: p.Channel,
+			Enabled: *p.Enabled,
+		}
+		key := string(p.Type) + "/" + string(p.Channel)
+		if i, ok := index[key]; ok {
+			prefs[i] = pref
+			continue
+		}
+		index[key] = len(prefs)
+		prefs = append(prefs, pref)
+	}
+
+	var updated []models.NotificationPreference
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if len(prefs) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "channel"}},
+				DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+			}).Create(&prefs).Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		updated, err = notify.Preferences(tx, CurrentUser.ID)
+		return err
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
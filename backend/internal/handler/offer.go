@@ -0,0 +1,311 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/models"
+	"api/internal/notify"
+	"api/internal/sales"
+	"errors"
+	"net/http"
+	"time"
+
+	database "api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// offerQuery preloads what the offer screens show
+func offerQuery(db *gorm.DB) *gorm.DB {
+	return db.Preload("Listing").Preload("Buyer", func(db *gorm.DB) *gorm.DB {
+		return db.Select(publicUserFields)
+	})
+}
+
+func parseOfferAmount(amount float64) error {
+	if amount <= 0 {
+		return apperror.ErrInvalidOfferAmount
+	}
+	return nil
+}
+
+// offerNotificationData is the data of the offer notifications
+func offerNotificationData(offer *models.Offer) models.JSONMap {
+	return models.JSONMap{
+		"offer_id":      offer.ID,
+		"listing_id":    offer.ListingID,
+		"listing_title": offer.Listing.Title,
+		"listing_slug":  offer.Listing.Slug,
+		"buyer_name":    offer.Buyer.DisplayName,
+		"amount":        offer.Amount,
+		"status":        offer.Status,
+	}
+}
+
+// CreateOffer makes an offer on a negotiable, available listing
+func CreateOffer(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var request struct {
+		Amount float64 `json:"amount" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+	if err := parseOfferAmount(request.Amount); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var listing models.Listing
+	if err := database.DB.First(&listing, "id = ?", c.Param("id")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrListingNotFound)
+		return
+	}
+	if listing.UserID == CurrentUser.ID {
+		apperror.Abort(c, apperror.ErrCannotOfferOwnListing)
+		return
+	}
+	if listing.Status != models.Available {
+		apperror.Abort(c, apperror.ErrListingNotAvailable)
+		return
+	}
+	if !listing.IsNegotiable {
+		apperror.Abort(c, apperror.ErrListingNotNegotiable)
+		return
+	}
+
+	offer := models.Offer{
+		ListingID:  listing.ID,
+		Listing:    listing,
+		BuyerID:    CurrentUser.ID,
+		Buyer:      CurrentUser,
+		Amount:     request.Amount,
+		ProposedBy: models.OfferByBuyer,
+		Rounds:     1,
+		Status:     models.OfferOpen,
+		ExpiresAt:  time.Now().Add(sales.OfferTTL),
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Listing", "Buyer").Create(&offer).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return apperror.ErrOfferAlreadyOpen
+			}
+			return err
+		}
+		return notify.Send(tx, listing.UserID, models.NotificationOfferReceived, offerNotificationData(&offer))
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, offer)
+}
+
+// GetListingOffers lists the offers on a listing of the current user, the
+// open ones by default (?status= picks another status, ?status=all every offer)
+func GetListingOffers(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var listing models.Listing
+	if err := database.DB.First(&listing, "id = ?", c.Param("id")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrListingNotFound)
+		return
+	}
+	if listing.UserID != CurrentUser.ID {
+		apperror.Abort(c, apperror.ErrNotListingOwner)
+		return
+	}
+
+	query, err := offerStatusFilter(c, database.DB.Where("listing_id = ?", listing.ID))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var offers []models.Offer
+	if err := offerQuery(query).Order("updated_at DESC").Find(&offers).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, offers)
+}
+
+// GetMyOffers lists the offers made by the current user, with the same ?status= filter
+func GetMyOffers(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	query, err := offerStatusFilter(c, database.DB.Where("buyer_id = ?", CurrentUser.ID))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var offers []models.Offer
+	if err := offerQuery(query).Order("updated_at DESC").Find(&offers).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, offers)
+}
+
+func offerStatusFilter(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	status := models.OfferStatus(c.DefaultQuery("status", string(models.OfferOpen)))
+	switch status {
+	case "all":
+		return query, nil
+	case models.OfferOpen, models.OfferAccepted, models.OfferRejected, models.OfferWithdrawn, models.OfferExpired:
+		return query.Where("status = ?", status), nil
+	default:
+		return nil, apperror.InvalidParam("status")
+	}
+}
+
+// answerOffer locks an open offer the current user takes part in and runs
+// answer with the party of the user. Other users get ErrOfferNotFound.
+func answerOffer(c *gin.Context, answer func(tx *gorm.DB, offer *models.Offer, party models.OfferParty) error) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidParam("id"))
+		return
+	}
+
+	var offer models.Offer
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := offerQuery(tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: clause.CurrentTable}})).
+			First(&offer, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.ErrOfferNotFound
+			}
+			return err
+		}
+
+		var party models.OfferParty
+		switch CurrentUser.ID {
+		case offer.BuyerID:
+			party = models.OfferByBuyer
+		case offer.Listing.UserID:
+			party = models.OfferBySeller
+		default:
+			return apperror.ErrOfferNotFound
+		}
+
+		if offer.Status != models.OfferOpen || offer.ExpiresAt.Before(time.Now()) {
+			return apperror.ErrOfferNotOpen
+		}
+
+		return answer(tx, &offer, party)
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, offer)
+}
+
+// otherParty returns the user on the other side of the negotiation
+func otherParty(offer *models.Offer, party models.OfferParty) string {
+	if party == models.OfferByBuyer {
+		return offer.Listing.UserID
+	}
+	return offer.BuyerID
+}
+
+// closeOffer answers the last proposal with status and tells whoever made it
+func closeOffer(tx *gorm.DB, offer *models.Offer, party models.OfferParty, status models.OfferStatus) error {
+	if offer.ProposedBy == party {
+		return apperror.ErrOfferNotYourTurn
+	}
+
+	now := time.Now()
+	offer.Status = status
+	offer.AnsweredAt = &now
+	if err := tx.Model(offer).Select("status", "answered_at").Updates(offer).Error; err != nil {
+		return err
+	}
+
+	return notify.Send(tx, otherParty(offer, party), models.NotificationOfferAnswered, offerNotificationData(offer))
+}
+
+// AcceptOffer accepts the last proposal of the other party. The seller then
+// registers the sale, with the accepted amount as default price.
+func AcceptOffer(c *gin.Context) {
+	answerOffer(c, func(tx *gorm.DB, offer *models.Offer, party models.OfferParty) error {
+		if offer.Listing.Status != models.Available {
+			return apperror.ErrListingNotAvailable
+		}
+		return closeOffer(tx, offer, party, models.OfferAccepted)
+	})
+}
+
+// RejectOffer rejects the last proposal of the other party
+func RejectOffer(c *gin.Context) {
+	answerOffer(c, func(tx *gorm.DB, offer *models.Offer, party models.OfferParty) error {
+		return closeOffer(tx, offer, party, models.OfferRejected)
+	})
+}
+
+// CounterOffer answers the last proposal of the other party with a new amount
+func CounterOffer(c *gin.Context) {
+	var request struct {
+		Amount float64 `json:"amount" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+	if err := parseOfferAmount(request.Amount); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	answerOffer(c, func(tx *gorm.DB, offer *models.Offer, party models.OfferParty) error {
+		if offer.ProposedBy == party {
+			return apperror.ErrOfferNotYourTurn
+		}
+		if offer.Listing.Status != models.Available {
+			return apperror.ErrListingNotAvailable
+		}
+
+		offer.Amount = request.Amount
+		offer.ProposedBy = party
+		offer.Rounds++
+		offer.ExpiresAt = time.Now().Add(sales.OfferTTL)
+		if err := tx.Model(offer).Select("amount", "proposed_by", "rounds", "expires_at", "updated_at").Updates(offer).Error; err != nil {
+			return err
+		}
+
+		notification := models.NotificationOfferReceived
+		if party == models.OfferBySeller {
+			notification = models.NotificationOfferCountered
+		}
+		return notify.Send(tx, otherParty(offer, party), notification, offerNotificationData(offer))
+	})
+}
+
+// WithdrawOffer closes an open offer, by its buyer
+func WithdrawOffer(c *gin.Context) {
+	answerOffer(c, func(tx *gorm.DB, offer *models.Offer, party models.OfferParty) error {
+		if party != models.OfferByBuyer {
+			return apperror.ErrNotOfferBuyer
+		}
+
+		now := time.Now()
+		offer.Status = models.OfferWithdrawn
+		offer.AnsweredAt = &now
+		return tx.Model(offer).Select("status", "answered_at").Updates(offer).Error
+	})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/audit"
+	"api/internal/models"
+	"api/internal/reconcile"
+	database "api/internal/repository"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ReconcileRequest struct {
+	DryRun *bool `json:"dry_run"` // nil means true: deleting must be asked for
+}
+
+// GetReconcileRuns lists the reconciler reports, newest first
+func GetReconcileRuns(c *gin.Context) {
+	pagination, err := parsePaginationParams(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.ReconcileRun{}).Count(&total).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var runs []models.ReconcileRun
+	err = database.DB.Order("started_at DESC").
+		Limit(pagination.PageSize).
+		Offset((pagination.Page - 1) * pagination.PageSize).
+		Find(&runs).Error
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	sendPaginatedResponse(c, runs, pagination, total)
+}
+
+func GetReconcileRun(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidParam("id"))
+		return
+	}
+
+	var run models.ReconcileRun
+	if err := database.DB.Where("id = ?", id).First(&run).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrReconcileRunNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// TriggerReconcile runs the reconciler now and returns its report
+func TriggerReconcile(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	CurrentUser := user.(models.User)
+
+	var req ReconcileRequest
+	// body opcional: sem body, só gera o relatório
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apperror.Abort(c, apperror.InvalidBody(err))
+			return
+		}
+	}
+
+	// Not tied to the request context, so a dropped connection doesn't leave a half-done run
+	run, err := reconcile.Run(context.Background(), reconcile.Options{
+		DryRun:      req.DryRun == nil || *req.DryRun,
+		GracePeriod: reconcile.GracePeriodFromEnv(),
+		Trigger:     "manual",
+		TriggeredBy: &CurrentUser.ID,
+	})
+	if err != nil {
+		if errors.Is(err, reconcile.ErrAlreadyRunning) {
+			apperror.Abort(c, apperror.ErrReconcileAlreadyRunning)
+			return
+		}
+		apperror.Abort(c, err)
+		return
+	}
+
+	// The run commits its own changes as it goes, so it is logged once done
+	if err := audit.Record(database.DB, c, audit.Entry{
+		Action:     models.AuditStorageReconcile,
+		TargetType: models.AuditTargetReconcile,
+		TargetID:   run.ID,
+		After:      run,
+	}); err != nil {
+		log.Printf("⚠️ failed to record reconcile run %s in the audit log: %v", run.ID, err)
+	}
+
+	c.JSON(http.StatusOK, run)
+}
@@ -1,13 +1,22 @@
 package handler
 
 import (
+	"api/internal/apperror"
+	"api/internal/audit"
 	"api/internal/models"
+	"api/internal/moderation"
+	"api/internal/notify"
 	"api/internal/repository"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateReport faz a criação de uma denúncia
@@ -15,16 +24,39 @@ func CreateReport(c *gin.Context) {
 	user, _ := c.Get("currentUser")
 	currentUser := user.(models.User)
 
-	var report models.Report
-	if err := c.ShouldBindJSON(&report); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request struct {
+		TargetType models.ReportTargetType `json:"target_type" binding:"required"`
+		TargetID   string                  `json:"target_id" binding:"required"`
+		Reason     models.ReportReason     `json:"reason" binding:"required"`
+		Details    string                  `json:"details"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
 
+	report := models.Report{
+		TargetType: request.TargetType,
+		TargetID:   request.TargetID,
+		Reason:     request.Reason,
+		Details:    request.Details,
+	}
+
+	if !report.TargetType.Valid() {
+		apperror.Abort(c, apperror.ErrInvalidReportTarget)
+		return
+	}
+	if report.TargetType == models.TargetTypeReview {
+		if err := repository.DB.Select("id").First(&models.Review{}, "id = ?", report.TargetID).Error; err != nil {
+			abortNotFound(c, err, apperror.ErrReviewNotFound)
+			return
+		}
+	}
+
 	report.ReporterID = currentUser.ID
 
 	if err := repository.DB.Create(&report).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -48,14 +80,17 @@ type ReportResponse struct {
 func GetReports(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
-	status := c.Query("status") // 'open' ou 'closed'
+	status := c.Query("status")     // 'open' ou 'closed'
+	assignee := c.Query("assignee") // 'me', 'none' ou o id de um admin
 
 	offset := (page - 1) * pageSize
 
 	var reports []models.Report
 	var total int64
 
-	query := repository.DB.Preload("Reporter")
+	query := repository.DB.Preload("Reporter").Preload("Assignee", func(db *gorm.DB) *gorm.DB {
+		return db.Select(publicUserFields)
+	})
 
 	// Lógica de filtro (procura todos se status não for especificado)
 	switch status {
@@ -65,11 +100,22 @@ func GetReports(c *gin.Context) {
 		query = query.Where("status IN ?", []string{"resolved", "rejected"})
 	}
 
+	switch assignee {
+	case "":
+	case "none":
+		query = query.Where("assignee_id IS NULL")
+	case "me":
+		user, _ := c.Get("currentUser")
+		query = query.Where("assignee_id = ?", user.(models.User).ID)
+	default:
+		query = query.Where("assignee_id = ?", assignee)
+	}
+
 	query.Model(&models.Report{}).Count(&total)
 
 	err := query.Order("created_at desc").Limit(pageSize).Offset(offset).Find(&reports).Error
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reports"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -96,6 +142,19 @@ func GetReports(c *gin.Context) {
 			repository.DB.Model(&models.User{}).Where("slug = ?", report.TargetID).First(&user)
 			dr.TargetName = user.DisplayName
 			dr.TargetSlug = user.Slug
+		case models.TargetTypeReview:
+			// The comment of the review, linking to the profile of the reviewed user
+			var review struct {
+				Comment string
+				Slug    string
+			}
+			repository.DB.Model(&models.Review{}).
+				Select("reviews.comment, users.slug").
+				Joins("JOIN users ON users.id = reviews.reviewed_id").
+				Where("reviews.id = ?", report.TargetID).
+				First(&review)
+			dr.TargetName = review.Comment
+			dr.TargetSlug = review.Slug
 		}
 		detailedReports = append(detailedReports, dr)
 	}
@@ -108,41 +167,320 @@ func GetReports(c *gin.Context) {
 	})
 }
 
-// GetReport recupera uma denúncia específica pelo ID
+// reportDetailQuery carrega a denúncia com o responsável, as notas e o histórico
+func reportDetailQuery() *gorm.DB {
+	publicUser := func(db *gorm.DB) *gorm.DB { return db.Select(publicUserFields) }
+	return repository.DB.
+		Preload("Reporter").
+		Preload("Assignee", publicUser).
+		Preload("Notes", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("Notes.Author", publicUser).
+		Preload("History", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("History.ChangedBy", publicUser)
+}
+
+// GetReport recupera uma denúncia específica pelo ID, com as notas e o histórico
 func GetReport(c *gin.Context) {
 	id := c.Param("id")
 	var report models.Report
-	if err := repository.DB.Preload("Reporter").First(&report, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+	if err := reportDetailQuery().First(&report, "id = ?", id).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrReportNotFound)
 		return
 	}
 
 	c.JSON(http.StatusOK, report)
 }
 
-// UpdateReportStatus atualiza o status de uma denúncia
-func UpdateReportStatus(c *gin.Context) {
-	id := c.Param("id")
+// AssignReport atribui a denúncia a um admin, ou remove o responsável com assignee_id nulo
+func AssignReport(c *gin.Context) {
+	var request struct {
+		AssigneeID *string `json:"assignee_id"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
 	var report models.Report
-	if err := repository.DB.First(&report, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+	if err := repository.DB.First(&report, "id = ?", c.Param("id")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrReportNotFound)
+		return
+	}
+
+	if request.AssigneeID != nil {
+		var assignee models.User
+		if err := repository.DB.Select("id, role").First(&assignee, "id = ?", *request.AssigneeID).Error; err != nil {
+			abortNotFound(c, err, apperror.ErrInvalidAssignee)
+			return
+		}
+		if !assignee.Role.Can(models.PermReportsResolve) {
+			apperror.Abort(c, apperror.ErrInvalidAssignee)
+			return
+		}
+	}
+
+	before := report
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&report).Update("assignee_id", request.AssigneeID).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditReportAssign,
+			TargetType: models.AuditTargetReport,
+			TargetID:   report.ID,
+			Before:     before,
+			After:      report,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if err := reportDetailQuery().First(&report, "id = ?", report.ID).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
+	c.JSON(http.StatusOK, report)
+}
+
+// AddReportNote adiciona uma nota interna à denúncia
+func AddReportNote(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	currentUser := user.(models.User)
+
 	var request struct {
-		Status models.ReportStatus `json:"status"`
+		Body string `json:"body" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+	body := strings.TrimSpace(request.Body)
+	if body == "" {
+		apperror.Abort(c, apperror.ErrEmptyMessage)
+		return
+	}
+
+	var report models.Report
+	if err := repository.DB.Select("id").First(&report, "id = ?", c.Param("id")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrReportNotFound)
+		return
+	}
+
+	note := models.ReportNote{ReportID: report.ID, AuthorID: &currentUser.ID, Body: body}
+	if err := repository.DB.Create(&note).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	report.Status = request.Status
-	now := time.Now()
-	report.ResolvedAt = &now
+	if err := repository.DB.Preload("Author", func(db *gorm.DB) *gorm.DB {
+		return db.Select(publicUserFields)
+	}).First(&note, "id = ?", note.ID).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// reportActionRequest é a ação aplicada ao resolver uma denúncia
+type reportActionRequest struct {
+	Type    models.ReportAction `json:"type" binding:"required"`
+	Days    int                 `json:"days"`    // suspend_user
+	Message string              `json:"message"` // aviso ou motivo enviado ao usuário
+}
+
+// reportedUserID resolve o usuário responsável pelo alvo da denúncia
+func reportedUserID(tx *gorm.DB, report models.Report) (string, error) {
+	var userID string
+	var err error
+	switch report.TargetType {
+	case models.TargetTypeProduct:
+		err = tx.Model(&models.Listing{}).Where("id = ?", report.TargetID).Pluck("user_id", &userID).Error
+	case models.TargetTypeUser:
+		err = tx.Model(&models.User{}).Where("slug = ?", report.TargetID).Pluck("id", &userID).Error
+	case models.TargetTypeReview:
+		err = tx.Model(&models.Review{}).Where("id = ?", report.TargetID).Pluck("reviewer_id", &userID).Error
+	}
+	if err == nil && userID == "" {
+		err = apperror.ErrReportTargetNotFound
+	}
+	return userID, err
+}
+
+// applyReportAction aplica a ação no alvo da denúncia e retorna os detalhes registrados
+func applyReportAction(tx *gorm.DB, report models.Report, action reportActionRequest, admin models.User) (models.JSONMap, error) {
+	reason := strings.TrimSpace(action.Message)
+	data := models.JSONMap{}
+	if reason != "" {
+		data["message"] = reason
+	}
+
+	if action.Type == models.ActionHideListing {
+		listingID, err := uuid.Parse(report.TargetID)
+		if err != nil {
+			return nil, apperror.ErrReportTargetNotFound
+		}
+		listing, err := moderation.HideListing(tx, listingID, reason)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.ErrReportTargetNotFound
+		}
+		data["listing_id"] = listing.ID
+		data["user_id"] = listing.UserID
+		return data, err
+	}
+
+	userID, err := reportedUserID(tx, report)
+	if err != nil {
+		return nil, err
+	}
+	data["user_id"] = userID
+
+	switch action.Type {
+	case models.ActionWarnUser:
+		return data, moderation.Warn(tx, userID, reason)
+	case models.ActionSuspendUser:
+		// Mesmas regras da suspensão direta (SuspendUser)
+		var user models.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return nil, err
+		}
+		if err := checkCanSanction(admin, user); err != nil {
+			return nil, err
+		}
+
+		until := time.Now().AddDate(0, 0, action.Days)
+		data["days"] = action.Days
+		data["until"] = until
+		return data, moderation.Suspend(tx, models.UserSanction{
+			UserID:   userID,
+			Reason:   reason,
+			EndsAt:   &until,
+			AdminID:  &admin.ID,
+			ReportID: &report.ID,
+		})
+	}
+	return nil, apperror.ErrInvalidReportAction
+}
+
+// UpdateReportStatus muda o status de uma denúncia, registrando a transição no
+// histórico. Ao resolver, o admin pode aplicar uma ação no alvo na mesma transação.
+func UpdateReportStatus(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	currentUser := user.(models.User)
+
+	var request struct {
+		Status models.ReportStatus  `json:"status" binding:"required"`
+		Action *reportActionRequest `json:"action"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	switch request.Status {
+	case models.StatusOpen, models.StatusResolved, models.StatusRejected:
+	default:
+		apperror.Abort(c, apperror.ErrInvalidReportStatus)
+		return
+	}
+
+	if action := request.Action; action != nil {
+		if request.Status != models.StatusResolved {
+			apperror.Abort(c, apperror.ErrReportActionNotAllowed)
+			return
+		}
+		if !action.Type.Valid() {
+			apperror.Abort(c, apperror.ErrInvalidReportAction)
+			return
+		}
+		if action.Type == models.ActionSuspendUser && (action.Days < 1 || action.Days > moderation.MaxSuspensionDays) {
+			apperror.Abort(c, apperror.ErrInvalidSuspensionDays.With("max", moderation.MaxSuspensionDays))
+			return
+		}
+	}
+
+	var report models.Report
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, "id = ?", c.Param("id")).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.ErrReportNotFound
+			}
+			return err
+		}
+
+		if report.Status == request.Status {
+			return apperror.ErrReportStatusUnchanged
+		}
+		before := report
+		if request.Action != nil && request.Action.Type == models.ActionHideListing && report.TargetType != models.TargetTypeProduct {
+			return apperror.ErrInvalidReportAction
+		}
+
+		change := models.ReportStatusChange{
+			ReportID:    report.ID,
+			From:        report.Status,
+			To:          request.Status,
+			ChangedByID: &currentUser.ID,
+		}
+
+		report.Status = request.Status
+		if request.Status == models.StatusOpen {
+			// Reaberta: a resolução e a ação anteriores continuam no histórico
+			report.ResolvedAt = nil
+			report.ResolvedByID = nil
+			report.Action = nil
+			report.ActionData = nil
+		} else {
+			now := time.Now()
+			report.ResolvedAt = &now
+			report.ResolvedByID = &currentUser.ID
+		}
+
+		if request.Action != nil {
+			data, err := applyReportAction(tx, report, *request.Action, currentUser)
+			if err != nil {
+				return err
+			}
+			report.Action = &request.Action.Type
+			report.ActionData = data
+			change.Action = &request.Action.Type
+		}
+
+		if err := tx.Model(&report).
+			Select("status", "resolved_at", "resolved_by_id", "action", "action_data").
+			Updates(&report).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&change).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditReportStatusUpdate,
+			TargetType: models.AuditTargetReport,
+			TargetID:   report.ID,
+			Before:     before,
+			After:      report,
+		}); err != nil {
+			return err
+		}
+
+		return notify.Send(tx, report.ReporterID, models.NotificationReportUpdated, models.JSONMap{
+			"report_id":   report.ID,
+			"status":      report.Status,
+			"target_type": report.TargetType,
+			"target_id":   report.TargetID,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
 
-	if err := repository.DB.Save(&report).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update report status"})
+	if err := reportDetailQuery().First(&report, "id = ?", report.ID).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
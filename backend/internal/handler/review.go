@@ -1,15 +1,56 @@
 package handler
 
 import (
+	"api/internal/apperror"
+	"api/internal/audit"
 	"api/internal/models"
+	"api/internal/notify"
+	"errors"
+	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	database "api/internal/repository"
 )
 
+// maxReviewReplyLength caps the reply to a review
+const maxReviewReplyLength = 1000
+
+// defaultReviewEditWindow is how long the author can edit or delete a review
+const defaultReviewEditWindow = 48 * time.Hour
+
+// reviewEditWindow reads REVIEW_EDIT_WINDOW (e.g. "72h"), falling back to defaultReviewEditWindow
+func reviewEditWindow() time.Duration {
+	if v := os.Getenv("REVIEW_EDIT_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("⚠️ invalid REVIEW_EDIT_WINDOW %q, using %s", v, defaultReviewEditWindow)
+	}
+	return defaultReviewEditWindow
+}
+
+// reviewQuery preloads the parties of the reviews with their public fields
+func reviewQuery() *gorm.DB {
+	publicUser := func(db *gorm.DB) *gorm.DB { return db.Select(publicUserFields) }
+	return database.DB.
+		Preload("Sale.Seller", publicUser).
+		Preload("Sale.Buyer", publicUser).
+		Preload("Sale.Listing").
+		Preload("Reviewer", publicUser).
+		Preload("Reviewed", publicUser)
+}
+
+// CreateReview reviews the other side of a confirmed sale: the buyer reviews
+// the seller and the seller reviews the buyer, once each
 func CreateReview(c *gin.Context) {
 	id := c.Param("id")
 
@@ -17,77 +58,379 @@ func CreateReview(c *gin.Context) {
 	currentUser := user.(models.User)
 
 	var requestBody struct {
-		Rating  int    `json:"rating"`
+		Rating  int    `json:"rating" binding:"required,min=1,max=5"`
 		Comment string `json:"comment"`
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
 
 	var sale models.Sale
 
 	if err := database.DB.First(&sale, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Sale not found"})
+		abortNotFound(c, err, apperror.ErrSaleNotFound)
 		return
 	}
 
-	if sale.BuyerID == nil || *sale.BuyerID != currentUser.ID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to review this sale"})
+	var review models.Review
+	switch {
+	case sale.BuyerID != nil && *sale.BuyerID == currentUser.ID:
+		review.Role = models.ReviewByBuyer
+		review.ReviewedID = sale.SellerID
+	case sale.SellerID == currentUser.ID && sale.BuyerID != nil:
+		review.Role = models.ReviewBySeller
+		review.ReviewedID = *sale.BuyerID
+	default:
+		apperror.Abort(c, apperror.ErrNotSaleParticipant)
 		return
 	}
 
-	var review models.Review
+	if sale.Status != models.SaleConfirmed {
+		apperror.Abort(c, apperror.ErrSaleNotConfirmed)
+		return
+	}
 
 	review.ID = uuid.New()
 	review.SaleID = sale.ID
+	review.ReviewerID = currentUser.ID
 	review.Rating = requestBody.Rating
 	review.Comment = requestBody.Comment
 
-	if err := database.DB.Create(&review).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Review"})
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&review).Error; err != nil {
+			return err
+		}
+		return notify.Send(tx, review.ReviewedID, models.NotificationReviewReceived, models.JSONMap{
+			"review_id": review.ID,
+			"sale_id":   sale.ID,
+			"rating":    review.Rating,
+			"role":      string(review.Role),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			apperror.Abort(c, apperror.ErrReviewAlreadyExists)
+			return
+		}
+		apperror.Abort(c, err)
 		return
 	}
 
-	if err := database.DB.First(&review, "id = ?", review.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load related data"})
+	if err := reviewQuery().First(&review, "id = ?", review.ID).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, review)
 }
 
-func GetReviewsReceived(c *gin.Context) {
-	user_slug := c.Param("user_slug")
+// ReplyToReview posts the public reply of the reviewed user, only once
+func ReplyToReview(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	currentUser := user.(models.User)
 
-	var user models.User
-	if err := database.DB.Where("slug = ?", user_slug).Find(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve User"})
+	var requestBody struct {
+		Reply string `json:"reply" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
 	}
 
-	var reviews []models.Review
+	reply := strings.TrimSpace(requestBody.Reply)
+	if reply == "" {
+		apperror.Abort(c, apperror.ErrEmptyMessage)
+		return
+	}
+	if utf8.RuneCountInString(reply) > maxReviewReplyLength {
+		apperror.Abort(c, apperror.ErrMessageTooLong.With("max", maxReviewReplyLength))
+		return
+	}
 
-	if err := database.DB.Preload("Sale.Seller").Preload("Sale.Buyer").Preload("Sale.Listing").Joins("JOIN sales ON sales.id = reviews.sale_id").Where("sales.seller_id = ?", user.ID).Order("reviews.created_at DESC").Find(&reviews).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve Reviews"})
+	var review models.Review
+	if err := database.DB.First(&review, "id = ?", c.Param("id")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrReviewNotFound)
+		return
+	}
+	if review.ReviewedID != currentUser.ID {
+		apperror.Abort(c, apperror.ErrNotReviewedUser)
+		return
+	}
+	if review.HiddenAt != nil {
+		apperror.Abort(c, apperror.ErrReviewHidden)
 		return
 	}
 
-	c.JSON(http.StatusOK, reviews)
+	// Only the first reply is kept, even with concurrent requests
+	now := time.Now()
+	result := database.DB.Model(&review).Where("reply IS NULL").Updates(map[string]any{"reply": reply, "replied_at": now})
+	if result.Error != nil {
+		apperror.Abort(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		apperror.Abort(c, apperror.ErrReplyAlreadyExists)
+		return
+	}
+
+	if err := reviewQuery().First(&review, "id = ?", review.ID).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
 }
 
+// authorReview loads the review :id and checks the current user can still change it
+func authorReview(c *gin.Context, tx *gorm.DB) (models.Review, bool) {
+	user, _ := c.Get("currentUser")
+	currentUser := user.(models.User)
+
+	var review models.Review
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, "id = ?", c.Param("id")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrReviewNotFound)
+		return review, false
+	}
+	if review.ReviewerID != currentUser.ID {
+		apperror.Abort(c, apperror.ErrNotReviewAuthor)
+		return review, false
+	}
+	if review.HiddenAt != nil {
+		apperror.Abort(c, apperror.ErrReviewHidden)
+		return review, false
+	}
+	if time.Since(review.CreatedAt) > reviewEditWindow() {
+		apperror.Abort(c, apperror.ErrReviewEditWindowClosed)
+		return review, false
+	}
+	return review, true
+}
+
+// UpdateReview lets the author change the rating and the comment within the edit window
+func UpdateReview(c *gin.Context) {
+	var requestBody struct {
+		Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	var review models.Review
+	var ok bool
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if review, ok = authorReview(c, tx); !ok {
+			return nil
+		}
+		return tx.Model(&review).Updates(map[string]any{
+			"rating":    requestBody.Rating,
+			"comment":   requestBody.Comment,
+			"edited_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	if err := reviewQuery().First(&review, "id = ?", review.ID).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// DeleteReview lets the author delete the review within the edit window. A
+// review with an open report stays until the report is handled.
+func DeleteReview(c *gin.Context) {
+	var ok bool
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		if review, ok = authorReview(c, tx); !ok {
+			return nil
+		}
+
+		var reports int64
+		if err := tx.Model(&models.Report{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", models.TargetTypeReview, review.ID.String(), models.StatusOpen).
+			Count(&reports).Error; err != nil {
+			return err
+		}
+		if reports > 0 {
+			return apperror.ErrReviewReported
+		}
+		return tx.Delete(&review).Error
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HideReview hides a review from the public lists and the reputation. The
+// review is kept with the admin and the reason.
+func HideReview(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	currentUser := user.(models.User)
+
+	var requestBody struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+	reason := strings.TrimSpace(requestBody.Reason)
+	if reason == "" {
+		apperror.Abort(c, apperror.InvalidParam("reason"))
+		return
+	}
+
+	var review models.Review
+	if err := database.DB.First(&review, "id = ?", c.Param("id")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrReviewNotFound)
+		return
+	}
+
+	before := review
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&review).Where("hidden_at IS NULL").Updates(map[string]any{
+			"hidden_at":     time.Now(),
+			"hidden_by_id":  currentUser.ID,
+			"hidden_reason": reason,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperror.ErrReviewHidden
+		}
+		if err := tx.First(&review, "id = ?", review.ID).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditReviewHide,
+			TargetType: models.AuditTargetReview,
+			TargetID:   review.ID,
+			Before:     before,
+			After:      review,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if err := reviewQuery().Preload("HiddenBy", func(db *gorm.DB) *gorm.DB { return db.Select(publicUserFields) }).First(&review, "id = ?", review.ID).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// UnhideReview makes a hidden review public again
+func UnhideReview(c *gin.Context) {
+	var review models.Review
+	if err := database.DB.First(&review, "id = ?", c.Param("id")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrReviewNotFound)
+		return
+	}
+
+	before := review
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&review).Where("hidden_at IS NOT NULL").Updates(map[string]any{
+			"hidden_at":     nil,
+			"hidden_by_id":  nil,
+			"hidden_reason": nil,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperror.ErrReviewNotHidden
+		}
+		if err := tx.First(&review, "id = ?", review.ID).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditReviewUnhide,
+			TargetType: models.AuditTargetReview,
+			TargetID:   review.ID,
+			Before:     before,
+			After:      review,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if err := reviewQuery().First(&review, "id = ?", review.ID).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// parseReviewRoleParam reads the optional ?role= (buyer or seller) of the review lists
+func parseReviewRoleParam(c *gin.Context) (models.ReviewRole, error) {
+	role := models.ReviewRole(c.Query("role"))
+	switch role {
+	case "", models.ReviewByBuyer, models.ReviewBySeller:
+		return role, nil
+	default:
+		return "", apperror.InvalidParam("role")
+	}
+}
+
+// GetReviewsReceived lists the reviews a user received. ?role=buyer keeps the
+// reviews written by buyers (the user as seller), ?role=seller the opposite.
+func GetReviewsReceived(c *gin.Context) {
+	listReviews(c, "reviewed_id")
+}
+
+// GetReviewsSent lists the reviews a user wrote, with the same ?role= filter
 func GetReviewsSent(c *gin.Context) {
+	listReviews(c, "reviewer_id")
+}
+
+func listReviews(c *gin.Context, userColumn string) {
 	user_slug := c.Param("user_slug")
 
+	role, err := parseReviewRoleParam(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
 	var user models.User
-	if err := database.DB.Where("slug = ?", user_slug).Find(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve User"})
+	if err := database.DB.Where("slug = ?", user_slug).First(&user).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrUserNotFound)
+		return
 	}
 
-	var reviews []models.Review
+	query := reviewQuery().Where(userColumn+" = ? AND hidden_at IS NULL", user.ID)
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
 
-	if err := database.DB.Preload("Sale.Seller").Preload("Sale.Buyer").Preload("Sale.Listing").Joins("JOIN sales ON sales.id = reviews.sale_id").Where("sales.buyer_id = ?", user.ID).Order("reviews.created_at DESC").Find(&reviews).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve Reviews"})
+	var reviews []models.Review
+	if err := query.Order("created_at DESC").Find(&reviews).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
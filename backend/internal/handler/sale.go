@@ -1,106 +1,358 @@
 package handler
 
 import (
+	"api/internal/apperror"
 	"api/internal/models"
+	"api/internal/notify"
+	"api/internal/sales"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
 	database "api/internal/repository"
 )
 
+// CreateSale registers the sale of a listing by its owner. Naming a buyer
+// (by email or slug) creates a pending sale that reserves the listing until
+// the buyer confirms it; without a buyer the listing is sold right away.
+// Without final_price, the price is the offer of the buyer the seller
+// accepted, or the listing price.
 func CreateSale(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	currentUser := user.(models.User)
+
 	listingID := c.Param("id")
 
 	var requestBody struct {
-		BuyerIdentifier string  `json:"buyer_identifier"`
-		FinalPrice      float64 `json:"final_price"`
+		BuyerIdentifier string   `json:"buyer_identifier"`
+		FinalPrice      *float64 `json:"final_price"`
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+	if requestBody.FinalPrice != nil && *requestBody.FinalPrice < 0 {
+		apperror.Abort(c, apperror.ErrInvalidFinalPrice)
 		return
 	}
 
+	var newSale models.Sale
 	err := database.DB.Transaction(func(tx *gorm.DB) error {
 		var listing models.Listing
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&listing, "id = ?", listingID).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("listing not found")
-			} else {
-				return errors.New("failed to retrieve Listing")
+				return apperror.ErrListingNotFound
 			}
+			return err
 		}
 
-		if listing.Status != models.Available {
-			return errors.New("listing not available for sale")
+		if listing.UserID != currentUser.ID {
+			return apperror.ErrNotListingOwner
 		}
 
-		var buyerID *string
-
+		var buyer *models.User
 		if requestBody.BuyerIdentifier != "" {
-			var buyer models.User
-			if err := tx.Where("email = ? OR slug = ?", requestBody.BuyerIdentifier, requestBody.BuyerIdentifier).First(&buyer).Error; err != nil {
-				return errors.New("failed to retrieve Buyer")
+			var err error
+			if buyer, err = findBuyer(tx, requestBody.BuyerIdentifier, listing.UserID); err != nil {
+				return err
 			}
-			buyerID = &buyer.ID
 		}
 
-		listing.Status = models.Sold
-		if err := tx.Save(&listing).Error; err != nil {
+		if err := checkCanSell(tx, &listing, buyer); err != nil {
 			return err
 		}
 
-		newSale := models.Sale{
+		newSale = models.Sale{
 			ListingID:  listing.ID,
 			SellerID:   listing.UserID,
-			BuyerID:    buyerID,
-			FinalPrice: requestBody.FinalPrice,
+			FinalPrice: listing.Price,
+			Status:     models.SaleConfirmed,
+		}
+
+		if buyer != nil {
+			var accepted []models.Offer
+			if err := tx.Where("listing_id = ? AND buyer_id = ? AND status = ?", listing.ID, buyer.ID, models.OfferAccepted).
+				Order("answered_at DESC").Limit(1).Find(&accepted).Error; err != nil {
+				return err
+			}
+			if len(accepted) > 0 {
+				newSale.FinalPrice = accepted[0].Amount
+				newSale.OfferID = &accepted[0].ID
+			}
+		}
+		if requestBody.FinalPrice != nil {
+			newSale.FinalPrice = *requestBody.FinalPrice
+		}
+		listing.Status = models.Sold
+		listing.ReservedForID = nil
+		listing.ReservedUntil = nil
+
+		// The listing stays on hold for the buyer until they answer
+		if buyer != nil {
+			expiresAt := time.Now().Add(sales.ProposalTTL)
+			newSale.BuyerID = &buyer.ID
+			newSale.Status = models.SalePending
+			newSale.ExpiresAt = &expiresAt
+			listing.Status = models.Reserved
+			listing.ReservedForID = &buyer.ID
+			listing.ReservedUntil = &expiresAt
+		}
+
+		if err := tx.Save(&listing).Error; err != nil {
+			return err
 		}
 		if err := tx.Create(&newSale).Error; err != nil {
 			return err
 		}
-		c.Set("saleResult", newSale)
+		if newSale.Status == models.SaleConfirmed {
+			if err := sales.CloseOpenOffers(tx, listing.ID); err != nil {
+				return err
+			}
+		}
+
+		if newSale.BuyerID != nil {
+			if err := notify.Send(tx, *newSale.BuyerID, models.NotificationSaleProposed, models.JSONMap{
+				"sale_id":       newSale.ID,
+				"listing_id":    listing.ID,
+				"listing_title": listing.Title,
+				"final_price":   newSale.FinalPrice,
+				"expires_at":    newSale.ExpiresAt,
+			}); err != nil {
+				return err
+			}
+		}
 
 		return nil
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperror.Abort(c, err)
 		return
 	}
 
-	if saleResult, exists := c.Get("saleResult"); exists {
-		c.JSON(http.StatusCreated, saleResult)
-	} else {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sale"})
+	c.JSON(http.StatusCreated, newSale)
+}
+
+// findBuyer looks a buyer up by email or slug
+func findBuyer(tx *gorm.DB, identifier, sellerID string) (*models.User, error) {
+	var buyer models.User
+	if err := tx.Where("email = ? OR slug = ?", identifier, identifier).First(&buyer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.ErrBuyerNotFound
+		}
+		return nil, err
+	}
+	if buyer.ID == sellerID {
+		return nil, apperror.ErrCannotSellToSelf
 	}
+	return &buyer, nil
 }
 
+// checkCanSell checks that a (locked) listing can be sold to buyer, nil when
+// sold outside the platform. A reserved listing can only be sold to the buyer
+// it is reserved for, and not while another sale waits for confirmation.
+func checkCanSell(tx *gorm.DB, listing *models.Listing, buyer *models.User) error {
+	switch listing.Status {
+	case models.Available:
+		return nil
+	case models.Reserved:
+		pending, err := sales.HasPending(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return apperror.ErrListingNotAvailable
+		}
+		if listing.ReservedForID != nil && (buyer == nil || buyer.ID != *listing.ReservedForID) {
+			return apperror.ErrListingReservedForAnother
+		}
+		return nil
+	default:
+		return apperror.ErrListingNotAvailable
+	}
+}
+
+// answerSale locks a pending sale of the current user and runs answer on it.
+// allowed checks who may answer (the buyer or the seller).
+func answerSale(c *gin.Context, allowed func(sale *models.Sale, userID string) error, answer func(tx *gorm.DB, sale *models.Sale) error) {
+	user, _ := c.Get("currentUser")
+	currentUser := user.(models.User)
+
+	saleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidParam("id"))
+		return
+	}
+
+	var sale models.Sale
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Listing").First(&sale, "id = ?", saleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.ErrSaleNotFound
+			}
+			return err
+		}
+		if err := allowed(&sale, currentUser.ID); err != nil {
+			return err
+		}
+		if sale.Status != models.SalePending {
+			return apperror.ErrSaleNotPending.With("status", sale.Status)
+		}
+		if sale.ExpiresAt != nil && sale.ExpiresAt.Before(time.Now()) {
+			return apperror.ErrSaleExpired
+		}
+		return answer(tx, &sale)
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sale)
+}
+
+func isSaleBuyer(sale *models.Sale, userID string) error {
+	if sale.BuyerID == nil || *sale.BuyerID != userID {
+		return apperror.ErrNotSaleBuyer
+	}
+	return nil
+}
+
+func isSaleSeller(sale *models.Sale, userID string) error {
+	if sale.SellerID != userID {
+		return apperror.ErrNotSaleSeller
+	}
+	return nil
+}
+
+func saleNotificationData(sale *models.Sale) models.JSONMap {
+	return models.JSONMap{
+		"sale_id":       sale.ID,
+		"listing_id":    sale.ListingID,
+		"listing_title": sale.Listing.Title,
+		"status":        sale.Status,
+	}
+}
+
+// AcceptSale confirms a pending sale, by its buyer: the listing is sold
+func AcceptSale(c *gin.Context) {
+	answerSale(c, isSaleBuyer, func(tx *gorm.DB, sale *models.Sale) error {
+		now := time.Now()
+		sale.Status = models.SaleConfirmed
+		sale.RespondedAt = &now
+		sale.SoldAt = now
+		if err := tx.Model(sale).Select("status", "responded_at", "sold_at").Updates(sale).Error; err != nil {
+			return err
+		}
+
+		sale.Listing.Status = models.Sold
+		sale.Listing.ReservedForID = nil
+		sale.Listing.ReservedUntil = nil
+		if err := tx.Model(&sale.Listing).Select("status", "reserved_for_id", "reserved_until").Updates(&sale.Listing).Error; err != nil {
+			return err
+		}
+		if err := sales.CloseOpenOffers(tx, sale.ListingID); err != nil {
+			return err
+		}
+
+		return notify.Send(tx, sale.SellerID, models.NotificationSaleConfirmed, saleNotificationData(sale))
+	})
+}
+
+// RejectSale rejects a pending sale, by its buyer: the listing is available again
+func RejectSale(c *gin.Context) {
+	answerSale(c, isSaleBuyer, func(tx *gorm.DB, sale *models.Sale) error {
+		if err := sales.Close(tx, sale, models.SaleRejected); err != nil {
+			return err
+		}
+		return notify.Send(tx, sale.SellerID, models.NotificationSaleClosed, saleNotificationData(sale))
+	})
+}
+
+// CancelSale withdraws a pending sale, by its seller: the listing is available again
+func CancelSale(c *gin.Context) {
+	answerSale(c, isSaleSeller, func(tx *gorm.DB, sale *models.Sale) error {
+		if err := sales.Close(tx, sale, models.SaleCancelled); err != nil {
+			return err
+		}
+		return notify.Send(tx, *sale.BuyerID, models.NotificationSaleClosed, saleNotificationData(sale))
+	})
+}
+
+// GetSale returns a sale to its seller, its buyer or the staff
 func GetSale(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	currentUser := user.(models.User)
+
 	saleID := c.Param("id")
 
 	var sale models.Sale
 
-	if err := database.DB.Preload("Seller").Preload("Buyer").Preload("Listing").Preload("Review").Find(&sale, "id = ?", saleID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sale"})
+	if err := database.DB.First(&sale, "id = ?", saleID).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrSaleNotFound)
+		return
+	}
+
+	staff := checkCanModerateListings(c)
+	if isSaleSeller(&sale, currentUser.ID) != nil && isSaleBuyer(&sale, currentUser.ID) != nil && !staff {
+		apperror.Abort(c, apperror.ErrNotSaleParticipant)
+		return
+	}
+
+	// Reviews hidden by the moderation are only shown to the staff
+	reviews := func(db *gorm.DB) *gorm.DB {
+		if staff {
+			return db
+		}
+		return db.Where("hidden_at IS NULL")
+	}
+	if err := saleQuery(database.DB).Preload("Reviews", reviews).First(&sale, "id = ?", sale.ID).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, sale)
 }
 
+// saleQuery preloads the listing and only the public data of the parties
+func saleQuery(db *gorm.DB) *gorm.DB {
+	publicUser := func(db *gorm.DB) *gorm.DB { return db.Select(publicUserFields) }
+	return db.Preload("Seller", publicUser).Preload("Buyer", publicUser).Preload("Listing")
+}
+
+// saleStatusFilter applies the optional ?status= of the sale lists
+func saleStatusFilter(c *gin.Context) (*gorm.DB, error) {
+	status := models.SaleStatus(c.Query("status"))
+	switch status {
+	case "":
+		return database.DB, nil
+	case models.SalePending, models.SaleConfirmed, models.SaleRejected, models.SaleCancelled, models.SaleExpired:
+		return database.DB.Where("status = ?", status), nil
+	default:
+		return nil, apperror.InvalidParam("status")
+	}
+}
+
 func GetSalesAsBuyer(c *gin.Context) {
 	user, _ := c.Get("currentUser")
 	currentUser := user.(models.User)
 
+	query, err := saleStatusFilter(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
 	var sales []models.Sale
 
-	if err := database.DB.Preload("Seller").Preload("Listing").Preload("Review").Find(&sales, "buyer_id = ?", currentUser.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bought items"})
+	if err := saleQuery(query).Preload("Reviews").Order("sold_at DESC").Find(&sales, "buyer_id = ?", currentUser.ID).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -111,10 +363,16 @@ func GetSalesAsSeller(c *gin.Context) {
 	user, _ := c.Get("currentUser")
 	currentUser := user.(models.User)
 
+	query, err := saleStatusFilter(c)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
 	var sales []models.Sale
 
-	if err := database.DB.Preload("Buyer").Preload("Listing").Preload("Review").Find(&sales, "seller_id = ?", currentUser.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sold items"})
+	if err := saleQuery(query).Preload("Reviews").Order("sold_at DESC").Find(&sales, "seller_id = ?", currentUser.ID).Error; err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/config"
+	"api/internal/imaging"
+	"api/internal/storage"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServeLocalFile serves an object of the local storage backend
+func ServeLocalFile(c *gin.Context) {
+	p, err := config.LocalStorage.Path(c.Param("key"))
+	if err != nil {
+		apperror.Abort(c, apperror.ErrFileNotFound)
+		return
+	}
+
+	if info, err := os.Stat(p); err != nil || info.IsDir() {
+		apperror.Abort(c, apperror.ErrFileNotFound)
+		return
+	}
+
+	c.File(p)
+}
+
+// UploadLocalFile receives a presigned PUT for the local storage backend,
+// mirroring how clients upload straight to S3
+func UploadLocalFile(c *gin.Context) {
+	key := c.Param("key")
+	if len(key) > 0 && key[0] == '/' {
+		key = key[1:]
+	}
+
+	contentType := c.Query("content_type")
+	if err := config.LocalStorage.VerifyPresign(key, contentType, c.Query("expires"), c.Query("signature")); err != nil {
+		apperror.Abort(c, apperror.ErrInvalidUploadURL)
+		return
+	}
+
+	if c.ContentType() != contentType {
+		apperror.Abort(c, apperror.ErrContentTypeMismatch)
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, imaging.MaxUploadSize)
+	if err := config.LocalStorage.Put(c.Request.Context(), key, contentType, body); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			apperror.Abort(c, apperror.InvalidParam("key"))
+			return
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apperror.Abort(c, apperror.ErrFileTooLarge)
+			return
+		}
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"api/internal/apperror"
+	"api/internal/audit"
 	"api/internal/config"
 	"api/internal/models"
 	"api/internal/repository"
@@ -9,13 +11,31 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func GetUser(c *gin.Context) {
 	user, _ := c.Get("currentUser")
 	CurrentUser := user.(models.User)
 
-	c.JSON(http.StatusOK, gin.H{"user": CurrentUser})
+	unread, err := repository.UnreadMessageCount(CurrentUser.ID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	unreadNotifications, err := repository.UnreadNotificationCount(CurrentUser.ID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":                 CurrentUser,
+		"permissions":          CurrentUser.Role.Permissions(),
+		"unread_messages":      unread,
+		"unread_notifications": unreadNotifications,
+	})
 }
 
 func UpdateUser(c *gin.Context) {
@@ -26,11 +46,10 @@ func UpdateUser(c *gin.Context) {
 		PhotoURL *string `json:"photo_url"`
 		Whatsapp *string `json:"whatsapp"`
 		Telegram *string `json:"telegram"`
-		Verified *bool   `json:"verified"`
 	}
 	var request UpdateUserRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
 
@@ -44,13 +63,10 @@ func UpdateUser(c *gin.Context) {
 	if request.Telegram != nil {
 		CurrentUser.Telegram = request.Telegram
 	}
-	if request.Verified != nil {
-		CurrentUser.Verified = *request.Verified
-	}
 
 	// Save the updated user
 	if err := repository.DB.Save(&CurrentUser).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -64,6 +80,10 @@ func DeleteUser(c *gin.Context) {
 
 	// Use a transaction to ensure all or nothing is deleted
 	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkNotLastSuperadmin(tx, currentUser); err != nil {
+			return err
+		}
+
 		var listings []models.Listing
 		if err := tx.Where("user_id = ?", userID).Find(&listings).Error; err != nil {
 			return err
@@ -101,7 +121,7 @@ func DeleteUser(c *gin.Context) {
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user and associated data"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -109,7 +129,7 @@ func DeleteUser(c *gin.Context) {
 	ctx := c.Request.Context()
 	if err := config.AuthClient.DeleteUser(ctx, userID); err != nil {
 		// this is a critical error (user is deleted from the database but not from firebase) maybe we should log it to fix it later
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user from authentication service"})
+		apperror.Abort(c, apperror.ErrAuthProviderFailure.Wrap(err))
 		return
 	}
 
@@ -121,18 +141,14 @@ func CheckProfileOwnership(c *gin.Context) {
 
 	currentUser, exists := c.Get("currentUser")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apperror.Abort(c, apperror.ErrMissingToken)
 		return
 	}
 	loggedInUser := currentUser.(models.User)
 
 	var profileOwner models.User
 	if err := repository.DB.Where("slug = ?", profileSlug).First(&profileOwner).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"is_owner": false, "message": "Profile not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profile owner"})
-		}
+		abortNotFound(c, err, apperror.ErrUserNotFound)
 		return
 	}
 
@@ -147,21 +163,21 @@ func FindProfile(c *gin.Context) {
 	slug := c.Param("slug")
 
 	var user models.User
-	result := repository.DB.Where("slug=?", slug).Find(&user)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"messsage": result.Error.Error()})
+	if err := repository.DB.Where("slug=?", slug).First(&user).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrUserNotFound)
 		return
 	}
 
 	// Only public data
 	resp := models.Profile{
-		DisplayName: user.DisplayName,
-		Slug:        user.Slug,
-		PhotoURL:    user.PhotoURL,
-		University:  user.University,
-		Verified:    user.Verified,
-		CreatedAt:   user.CreatedAt,
-		Role:        user.Role,
+		DisplayName:   user.DisplayName,
+		Slug:          user.Slug,
+		PhotoURL:      user.PhotoURL,
+		University:    user.University,
+		InstitutionID: user.InstitutionID,
+		Verified:      user.Verified,
+		CreatedAt:     user.CreatedAt,
+		Role:          user.Role,
 	}
 
 	c.JSON(http.StatusOK, gin.H{"user": resp})
@@ -174,11 +190,7 @@ func GetProfileMetrics(c *gin.Context) {
 	// Finding the user by slug
 	var user models.User
 	if err := repository.DB.Where("slug=?", userSlug).First(&user).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
-		}
+		abortNotFound(c, err, apperror.ErrUserNotFound)
 		return
 	}
 
@@ -194,8 +206,8 @@ func GetProfileMetrics(c *gin.Context) {
 	}
 
 	// Counting active listings
-	repository.DB.Model(&models.Listing{}).Where("user_id = ? AND status = ?", user.ID, models.Available).Count(&metrics.ActiveListingsCount)
-	repository.DB.Model(&models.Listing{}).Where("user_id = ? AND status = ?", user.ID, models.Sold).Count(&metrics.ItemsSold)
+	repository.DB.Model(&models.Listing{}).Where("user_id = ? AND status IN ?", user.ID, models.ActiveStatuses).Count(&metrics.ActiveListingsCount)
+	repository.DB.Model(&models.Sale{}).Where("seller_id = ? AND status = ?", user.ID, models.SaleConfirmed).Count(&metrics.ItemsSold)
 
 	// Counting total listings
 	repository.DB.Model(&models.Listing{}).Where("user_id = ?", user.ID).Count(&metrics.TotalListingsCount)
@@ -207,6 +219,21 @@ func GetProfileMetrics(c *gin.Context) {
 		repository.DB.Model(&models.Favorite{}).Where("listing_id IN ?", listingIDs).Count(&metrics.TotalFavoritesCount)
 	}
 
+	// Reputation from the reviews received as seller and as buyer
+	reputation, err := repository.Reputation(user.ID, models.ReviewByBuyer)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	metrics.Reputation = reputation
+
+	buyerReputation, err := repository.Reputation(user.ID, models.ReviewBySeller)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	metrics.BuyerReputation = buyerReputation
+
 	// Setting the member since date
 	if !user.CreatedAt.IsZero() {
 		metrics.MemberSince = &user.CreatedAt
@@ -223,11 +250,7 @@ func GetProfileContact(c *gin.Context) {
 	var user models.User
 
 	if err := repository.DB.Where("slug = ?", slug).First(&user).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
-		}
+		abortNotFound(c, err, apperror.ErrUserNotFound)
 		return
 	}
 
@@ -245,7 +268,7 @@ func GetUsers(c *gin.Context) {
 
 	var qp QueryParams
 	if err := c.ShouldBindQuery(&qp); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		apperror.Abort(c, apperror.InvalidParam("page").Wrap(err))
 		return
 	}
 
@@ -261,14 +284,14 @@ func GetUsers(c *gin.Context) {
 
 	var total int64
 	if err := repository.DB.Model(&models.User{}).Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users"})
+		apperror.Abort(c, err)
 		return
 	}
 
 	offset := (qp.Page - 1) * qp.PageSize
 	var users []models.User
 	if err := repository.DB.Limit(qp.PageSize).Offset(offset).Find(&users).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -284,17 +307,23 @@ func DeleteUserByAdmin(c *gin.Context) {
 	userSlug := c.Param("slug")
 	var user models.User
 	if err := repository.DB.Where("slug = ?", userSlug).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user from database"})
+		abortNotFound(c, err, apperror.ErrUserNotFound)
+		return
+	}
+
+	current, _ := c.Get("currentUser")
+	if err := checkCanManage(current.(models.User), user); err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
 	userID := user.ID
 
 	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkNotLastSuperadmin(tx, user); err != nil {
+			return err
+		}
+
 		var listings []models.Listing
 		if err := tx.Where("user_id = ?", userID).Find(&listings).Error; err != nil {
 			return err
@@ -327,21 +356,23 @@ func DeleteUserByAdmin(c *gin.Context) {
 			return gorm.ErrRecordNotFound
 		}
 
-		return nil
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditUserDelete,
+			TargetType: models.AuditTargetUser,
+			TargetID:   userID,
+			Before:     user,
+		})
 	})
 
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found during transaction"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user and associated data"})
+		abortNotFound(c, err, apperror.ErrUserNotFound)
 		return
 	}
 
 	ctx := c.Request.Context()
 	if err := config.AuthClient.DeleteUser(ctx, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "User deleted from database, but failed to delete from authentication service"})
+		// User deleted from database, but not from the authentication service
+		apperror.Abort(c, apperror.ErrAuthProviderFailure.Wrap(err))
 		return
 	}
 
@@ -352,7 +383,7 @@ func UpdateUserRole(c *gin.Context) {
 	userSlug := c.Param("slug")
 	var user models.User
 	if err := repository.DB.First(&user, "slug = ?", userSlug).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		abortNotFound(c, err, apperror.ErrUserNotFound)
 		return
 	}
 
@@ -361,20 +392,66 @@ func UpdateUserRole(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		apperror.Abort(c, apperror.InvalidBody(err))
 		return
 	}
 
-	if request.Role != models.RoleUser && request.Role != models.RoleAdmin {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role specified"})
+	if !request.Role.Valid() {
+		apperror.Abort(c, apperror.ErrInvalidRole)
 		return
 	}
 
+	before := user
 	user.Role = request.Role
-	if err := repository.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if request.Role != models.RoleSuperadmin {
+			if err := checkNotLastSuperadmin(tx, before); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     models.AuditUserRoleUpdate,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID,
+			Before:     before,
+			After:      user,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
+
+// checkNotLastSuperadmin fails when user is the only superadmin left. The
+// superadmins stay locked until tx ends, so two demotions can't race.
+func checkNotLastSuperadmin(tx *gorm.DB, user models.User) error {
+	if user.Role != models.RoleSuperadmin {
+		return nil
+	}
+
+	var ids []string
+	if err := tx.Model(&models.User{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ?", models.RoleSuperadmin).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) <= 1 {
+		return apperror.ErrLastSuperadmin
+	}
+	return nil
+}
+
+// checkCanManage keeps superadmins out of reach of the staff who can't grant roles
+func checkCanManage(staff, user models.User) error {
+	if user.Role == models.RoleSuperadmin && !staff.Role.Can(models.PermRolesGrant) {
+		return apperror.ErrMissingPermission.With("permission", models.PermRolesGrant)
+	}
+	return nil
+}
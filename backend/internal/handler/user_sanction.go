@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/audit"
+	"api/internal/models"
+	"api/internal/moderation"
+	"api/internal/repository"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// sanctionTarget loads the user :slug, who can't be the admin themselves
+func sanctionTarget(c *gin.Context) (models.User, models.User, bool) {
+	current, _ := c.Get("currentUser")
+	admin := current.(models.User)
+
+	var user models.User
+	if err := repository.DB.First(&user, "slug = ?", c.Param("slug")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrUserNotFound)
+		return user, admin, false
+	}
+	if err := checkCanSanction(admin, user); err != nil {
+		apperror.Abort(c, err)
+		return user, admin, false
+	}
+	return user, admin, true
+}
+
+// checkCanSanction keeps admins from sanctioning themselves or the superadmins.
+// Only a superadmin can reach another one, so the last superadmin stays reachable.
+func checkCanSanction(admin, user models.User) error {
+	if user.ID == admin.ID {
+		return apperror.ErrCannotSanctionSelf
+	}
+	return checkCanManage(admin, user)
+}
+
+// applySanction runs a moderation action in a transaction, records it in the
+// audit log and answers with the updated user
+func applySanction(c *gin.Context, user models.User, action string, apply func(tx *gorm.DB) error) {
+	before := user
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := apply(tx); err != nil {
+			return err
+		}
+		if err := tx.First(&user, "id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     action,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID,
+			Before:     before,
+			After:      user,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// SuspendUser suspends a user for `days` days, or until lifted when omitted.
+// While suspended the user can't log in and their listings are hidden.
+func SuspendUser(c *gin.Context) {
+	var request struct {
+		Reason string `json:"reason" binding:"required"`
+		Days   int    `json:"days"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+	if request.Days < 0 || request.Days > moderation.MaxSuspensionDays {
+		apperror.Abort(c, apperror.ErrInvalidSuspensionDays.With("max", moderation.MaxSuspensionDays))
+		return
+	}
+
+	user, admin, ok := sanctionTarget(c)
+	if !ok {
+		return
+	}
+
+	sanction := models.UserSanction{UserID: user.ID, Reason: strings.TrimSpace(request.Reason), AdminID: &admin.ID}
+	if request.Days > 0 {
+		until := time.Now().AddDate(0, 0, request.Days)
+		sanction.EndsAt = &until
+	}
+
+	applySanction(c, user, models.AuditUserSuspend, func(tx *gorm.DB) error {
+		return moderation.Suspend(tx, sanction)
+	})
+}
+
+// UnsuspendUser lifts the suspension of a user
+func UnsuspendUser(c *gin.Context) {
+	user, admin, ok := sanctionTarget(c)
+	if !ok {
+		return
+	}
+
+	applySanction(c, user, models.AuditUserUnsuspend, func(tx *gorm.DB) error {
+		return moderation.Unsuspend(tx, models.UserSanction{UserID: user.ID, AdminID: &admin.ID})
+	})
+}
+
+// BanUser permanently bans a user, keeping their data
+func BanUser(c *gin.Context) {
+	var request struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	user, admin, ok := sanctionTarget(c)
+	if !ok {
+		return
+	}
+
+	applySanction(c, user, models.AuditUserBan, func(tx *gorm.DB) error {
+		return moderation.Ban(tx, models.UserSanction{UserID: user.ID, Reason: strings.TrimSpace(request.Reason), AdminID: &admin.ID})
+	})
+}
+
+// UnbanUser lifts the ban of a user
+func UnbanUser(c *gin.Context) {
+	user, admin, ok := sanctionTarget(c)
+	if !ok {
+		return
+	}
+
+	applySanction(c, user, models.AuditUserUnban, func(tx *gorm.DB) error {
+		return moderation.Unban(tx, models.UserSanction{UserID: user.ID, AdminID: &admin.ID})
+	})
+}
+
+// GetUserSanctions lists the suspensions and bans of a user, newest first
+func GetUserSanctions(c *gin.Context) {
+	var user models.User
+	if err := repository.DB.Select("id").First(&user, "slug = ?", c.Param("slug")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrUserNotFound)
+		return
+	}
+
+	var sanctions []models.UserSanction
+	if err := repository.DB.Preload("Admin", func(db *gorm.DB) *gorm.DB {
+		return db.Select(publicUserFields)
+	}).Where("user_id = ?", user.ID).Order("created_at DESC").Find(&sanctions).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sanctions)
+}
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"api/internal/apperror"
+	"api/internal/audit"
+	"api/internal/config"
+	"api/internal/mail"
+	"api/internal/models"
+	"api/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	verificationCodeTTL     = 15 * time.Minute
+	verificationCodeResend  = time.Minute // minimum time between two codes
+	maxVerificationAttempts = 5
+)
+
+// verificationRequiresCode reads VERIFICATION_REQUIRE_CODE: when true, a
+// verified institutional email is not enough and the user must type a code
+// sent to it
+func verificationRequiresCode() bool {
+	required, _ := strconv.ParseBool(os.Getenv("VERIFICATION_REQUIRE_CODE"))
+	return required
+}
+
+// institutionalEmail returns the email of userID once Firebase confirmed it
+// and it belongs to an allowed institution
+func institutionalEmail(ctx context.Context, userID string) (string, error) {
+	record, err := config.AuthClient.GetUser(ctx, userID)
+	if err != nil {
+		return "", apperror.ErrAuthProviderFailure.Wrap(err)
+	}
+	if !record.EmailVerified {
+		return "", apperror.ErrEmailNotVerified
+	}
+	institution, err := repository.ResolveInstitution(record.Email)
+	if err != nil {
+		return "", err
+	}
+	if institution == nil {
+		return "", apperror.ErrEmailNotInstitutional
+	}
+	return record.Email, nil
+}
+
+// markVerified verifies userID with method. Self-verification (every method
+// but admin) is refused once an admin revoked the verification.
+func markVerified(tx *gorm.DB, userID string, method models.VerificationMethod) error {
+	query := tx.Model(&models.User{}).Where("id = ?", userID)
+	if method != models.VerifiedByAdmin {
+		query = query.Where("verification_revoked_at IS NULL")
+	}
+	result := query.Updates(map[string]any{
+		"verified":                true,
+		"verification_method":     method,
+		"verified_at":             time.Now(),
+		"verification_revoked_at": nil,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperror.ErrVerificationRevoked
+	}
+	return nil
+}
+
+// checkCanVerify fails when the user can't start verifying themselves
+func checkCanVerify(user models.User) error {
+	if user.Verified {
+		return apperror.ErrAlreadyVerified
+	}
+	if user.VerificationRevokedAt != nil {
+		return apperror.ErrVerificationRevoked
+	}
+	return nil
+}
+
+func hashVerificationCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// StartVerification verifies the current user from their institutional email.
+// With VERIFICATION_REQUIRE_CODE it emails a one-time code instead, to be
+// sent to ConfirmVerification.
+func StartVerification(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	currentUser := user.(models.User)
+
+	if err := checkCanVerify(currentUser); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	email, err := institutionalEmail(c.Request.Context(), currentUser.ID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if !verificationRequiresCode() {
+		if err := markVerified(repository.DB, currentUser.ID, models.VerifiedByInstitutionalEmail); err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		if err := repository.DB.First(&currentUser, "id = ?", currentUser.ID).Error; err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"user": currentUser})
+		return
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	verification := models.VerificationCode{
+		UserID:    currentUser.ID,
+		CodeHash:  hashVerificationCode(code),
+		Email:     email,
+		ExpiresAt: time.Now().Add(verificationCodeTTL),
+		CreatedAt: time.Now(),
+	}
+	err = repository.DB.Transaction(func(tx *gorm.DB) error {
+		var previous []models.VerificationCode
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", currentUser.ID).
+			Find(&previous).Error; err != nil {
+			return err
+		}
+		if len(previous) > 0 {
+			if wait := verificationCodeResend - time.Since(previous[0].CreatedAt); wait > 0 {
+				return apperror.ErrVerificationCodeRecentlySent.With("seconds", int(wait.Seconds())+1)
+			}
+		}
+
+		if err := tx.Save(&verification).Error; err != nil {
+			return err
+		}
+		return mail.Enqueue(tx, email, mail.TemplateVerificationCode, models.JSONMap{
+			"name":    currentUser.DisplayName,
+			"code":    code,
+			"minutes": int(verificationCodeTTL.Minutes()),
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, verification)
+}
+
+// ConfirmVerification checks the code sent by StartVerification
+func ConfirmVerification(c *gin.Context) {
+	user, _ := c.Get("currentUser")
+	currentUser := user.(models.User)
+
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apperror.Abort(c, apperror.InvalidBody(err))
+		return
+	}
+
+	if err := checkCanVerify(currentUser); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	// A wrong code must still count as an attempt, so it is not an error of the transaction
+	var codeErr error
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		var verification models.VerificationCode
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&verification, "user_id = ?", currentUser.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.ErrVerificationCodeExpired
+			}
+			return err
+		}
+
+		if time.Now().After(verification.ExpiresAt) || verification.Attempts >= maxVerificationAttempts {
+			codeErr = apperror.ErrVerificationCodeExpired
+			return tx.Delete(&verification).Error
+		}
+
+		given := hashVerificationCode(strings.TrimSpace(request.Code))
+		if subtle.ConstantTimeCompare([]byte(given), []byte(verification.CodeHash)) != 1 {
+			codeErr = apperror.ErrInvalidVerificationCode.With("attempts_left", maxVerificationAttempts-verification.Attempts-1)
+			return tx.Model(&verification).Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+
+		if err := markVerified(tx, currentUser.ID, models.VerifiedByEmailCode); err != nil {
+			return err
+		}
+		return tx.Delete(&verification).Error
+	})
+	if err == nil {
+		err = codeErr
+	}
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if err := repository.DB.First(&currentUser, "id = ?", currentUser.ID).Error; err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": currentUser})
+}
+
+// updateVerificationByStaff changes the verification of the user :slug and records it
+func updateVerificationByStaff(c *gin.Context, action string, apply func(tx *gorm.DB, user models.User) error) {
+	var user models.User
+	if err := repository.DB.First(&user, "slug = ?", c.Param("slug")).Error; err != nil {
+		abortNotFound(c, err, apperror.ErrUserNotFound)
+		return
+	}
+
+	before := user
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := apply(tx, user); err != nil {
+			return err
+		}
+		if err := tx.First(&user, "id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     action,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID,
+			Before:     before,
+			After:      user,
+		})
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// VerifyUser verifies a user by hand, lifting a previous revocation
+func VerifyUser(c *gin.Context) {
+	updateVerificationByStaff(c, models.AuditUserVerify, func(tx *gorm.DB, user models.User) error {
+		return markVerified(tx, user.ID, models.VerifiedByAdmin)
+	})
+}
+
+// RevokeVerification removes the verification of a user, who can't verify
+// themselves again until an admin verifies them
+func RevokeVerification(c *gin.Context) {
+	updateVerificationByStaff(c, models.AuditUserUnverify, func(tx *gorm.DB, user models.User) error {
+		if err := tx.Delete(&models.VerificationCode{}, "user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Updates(map[string]any{
+			"verified":                false,
+			"verification_method":     nil,
+			"verified_at":             nil,
+			"verification_revoked_at": time.Now(),
+		}).Error
+	})
+}
@@ -0,0 +1,130 @@
+// Package imaging turns user uploads into the re-encoded variants stored for listing images.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"slices"
+
+	_ "image/png" // register decoders used by image.Decode
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxUploadSize is the largest upload accepted, in bytes.
+const MaxUploadSize = 15 << 20
+
+// maxPixels guards against decompression bombs (tiny files with huge dimensions).
+const maxPixels = 50_000_000
+
+const jpegQuality = 82
+
+// ContentType of every variant produced by Process.
+const ContentType = "image/jpeg"
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTooLarge        = errors.New("image dimensions too large")
+)
+
+// AllowedTypes are the content types accepted, detected from the bytes (not the client header).
+var AllowedTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+// Size is a named target size; images are scaled to fit MaxSide without upscaling.
+type Size struct {
+	Name    string
+	MaxSide int
+}
+
+// Sizes produced for every listing image, smallest first.
+var Sizes = []Size{
+	{Name: "thumbnail", MaxSide: 320},
+	{Name: "card", MaxSide: 800},
+	{Name: "full", MaxSide: 1920},
+}
+
+// Variant is one re-encoded size of an upload.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// DetectContentType sniffs the content type from the first bytes of data.
+func DetectContentType(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+// Process validates data by sniffing its bytes, applies the EXIF orientation,
+// and re-encodes it as JPEG in every size of Sizes. Re-encoding drops all
+// metadata (EXIF, GPS, ICC, comments) from the original file.
+func Process(data []byte) ([]Variant, error) {
+	contentType := DetectContentType(data)
+	if !slices.Contains(AllowedTypes, contentType) {
+		return nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	if contentType == "image/jpeg" {
+		src = applyOrientation(src, jpegOrientation(data))
+	}
+
+	variants := make([]Variant, 0, len(Sizes))
+	for _, size := range Sizes {
+		resized := fit(src, size.MaxSide)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+
+		bounds := resized.Bounds()
+		variants = append(variants, Variant{
+			Name:   size.Name,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+			Data:   buf.Bytes(),
+		})
+	}
+
+	return variants, nil
+}
+
+// fit scales src so its longest side is at most maxSide, keeping the aspect ratio.
+// Transparent areas (PNG/WebP) are flattened onto white since JPEG has no alpha.
+func fit(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			h = max(1, h*maxSide/w)
+			w = maxSide
+		} else {
+			w = max(1, w*maxSide/h)
+			h = maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,179 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage returns a w x h image, red on the left half and blue on the right
+func testImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeJPEG encodes img with an Exif segment carrying the orientation tag
+// and a GPS marker, which must not survive Process
+func encodeJPEG(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// TIFF header, one IFD entry (orientation, SHORT) and no next IFD
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, "GPSLatitude-22.0"...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+// pngHeader is a PNG with only its IHDR chunk: enough for image.DecodeConfig
+func pngHeader(w, h uint32) []byte {
+	ihdr := binary.BigEndian.AppendUint32([]byte("IHDR"), w)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, h)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+
+	out := []byte("\x89PNG\r\n\x1a\n")
+	out = binary.BigEndian.AppendUint32(out, uint32(len(ihdr)-4))
+	out = append(out, ihdr...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestProcessSniffsContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"png", encodePNG(t, testImage(10, 10)), nil},
+		{"jpeg", encodeJPEG(t, testImage(10, 10), 1), nil},
+		{"text", []byte("just some text, not an image"), ErrUnsupportedType},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), ErrUnsupportedType},
+		{"truncated png", encodePNG(t, testImage(10, 10))[:40], ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Process() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestProcessVariants(t *testing.T) {
+	variants, err := Process(encodePNG(t, testImage(2400, 1200)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variants) != len(Sizes) {
+		t.Fatalf("got %d variants, want %d", len(variants), len(Sizes))
+	}
+
+	for i, v := range variants {
+		size := Sizes[i]
+		if v.Name != size.Name {
+			t.Errorf("variant %d is %q, want %q", i, v.Name, size.Name)
+		}
+		if v.Width != size.MaxSide || v.Height != size.MaxSide/2 {
+			t.Errorf("%s is %dx%d, want %dx%d", v.Name, v.Width, v.Height, size.MaxSide, size.MaxSide/2)
+		}
+		if got := DetectContentType(v.Data); got != ContentType {
+			t.Errorf("%s is %s, want %s", v.Name, got, ContentType)
+		}
+	}
+}
+
+func TestProcessDoesNotUpscale(t *testing.T) {
+	variants, err := Process(encodePNG(t, testImage(100, 50)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range variants {
+		if v.Width != 100 || v.Height != 50 {
+			t.Errorf("%s is %dx%d, want 100x50", v.Name, v.Width, v.Height)
+		}
+	}
+}
+
+func TestProcessAppliesOrientationAndStripsMetadata(t *testing.T) {
+	data := encodeJPEG(t, testImage(40, 20), 6)
+	if jpegOrientation(data) != 6 {
+		t.Fatalf("test image has orientation %d, want 6", jpegOrientation(data))
+	}
+
+	variants, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range variants {
+		// Rotated 90° clockwise: the width and height swap
+		if v.Width != 20 || v.Height != 40 {
+			t.Errorf("%s is %dx%d, want 20x40", v.Name, v.Width, v.Height)
+		}
+		if bytes.Contains(v.Data, []byte("Exif")) || bytes.Contains(v.Data, []byte("GPSLatitude")) {
+			t.Errorf("%s kept the Exif metadata", v.Name)
+		}
+		if jpegOrientation(v.Data) != 1 {
+			t.Errorf("%s still has an orientation", v.Name)
+		}
+	}
+
+	// The left (red) half of the original ends up on top
+	img, err := jpeg.Decode(bytes.NewReader(variants[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _, b, _ := img.At(10, 5).RGBA()
+	if r < b {
+		t.Errorf("top of the rotated image is not red")
+	}
+}
+
+func TestProcessRejectsTooManyPixels(t *testing.T) {
+	_, err := Process(pngHeader(10_000, 10_000))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Process() error = %v, want %v", err, ErrTooLarge)
+	}
+}
@@ -0,0 +1,157 @@
+// Package mail sends the transactional emails. Emails are written to the
+// outbox table (in the transaction of the change that caused them) and a
+// background worker delivers them over SMTP, retrying with backoff.
+package mail
+
+import (
+	"api/internal/models"
+	"api/internal/repository"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	pollInterval = 10 * time.Second
+	batchSize    = 20
+	// maxAttempts before an email is marked as failed, about 30 minutes of retries
+	maxAttempts = 6
+	// sendLease is how long a worker owns the emails it claimed. Emails still
+	// sending after it (the worker died) are claimed again.
+	sendLease = 10 * time.Minute
+)
+
+// Enqueue adds an email to the outbox. Pass the transaction of the change
+// that caused the email, so it is only sent if that change commits.
+func Enqueue(tx *gorm.DB, to, template string, data models.JSONMap) error {
+	if !HasTemplate(template) {
+		return fmt.Errorf("unknown email template %q", template)
+	}
+	return tx.Create(&models.OutboxEmail{
+		To:            to,
+		Template:      template,
+		Data:          data,
+		Status:        models.EmailPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// Work delivers the outbox until ctx is done. Without SMTP_HOST the emails
+// stay in the outbox.
+func Work(ctx context.Context) {
+	cfg, ok := configFromEnv()
+	if !ok {
+		log.Println("⚠️ SMTP_HOST not set, emails will stay in the outbox")
+		return
+	}
+	log.Printf("✅ Mail worker sending through %s", cfg.addr())
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		// Drain full batches right away, wait for the next tick otherwise
+		for {
+			n, err := processBatch(cfg)
+			if err != nil {
+				log.Printf("❌ mail worker: %v", err)
+			}
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processBatch sends the due emails. They are claimed in a short transaction
+// (rows locked with SKIP LOCKED, so several API replicas can run the worker),
+// sent outside of it, and each result is recorded on its own.
+func processBatch(cfg smtpConfig) (int, error) {
+	emails, err := claimBatch()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range emails {
+		email := &emails[i]
+		updates := map[string]any{}
+
+		if err := send(cfg, email); err != nil {
+			updates["last_error"] = err.Error()
+			if email.Attempts >= maxAttempts {
+				updates["status"] = models.EmailFailed
+				log.Printf("❌ giving up on email %s (%s) after %d attempts: %v", email.ID, email.Template, email.Attempts, err)
+			} else {
+				updates["status"] = models.EmailPending
+				updates["next_attempt_at"] = time.Now().Add(retryDelay(email.Attempts))
+			}
+		} else {
+			updates["status"] = models.EmailSent
+			updates["sent_at"] = time.Now()
+			updates["last_error"] = nil
+		}
+
+		if err := repository.DB.Model(&models.OutboxEmail{}).
+			Where("id = ? AND status = ?", email.ID, models.EmailSending).
+			Updates(updates).Error; err != nil {
+			return len(emails), err
+		}
+	}
+	return len(emails), nil
+}
+
+// claimBatch marks the due emails, and those whose lease expired, as sending
+// for sendLease and counts the attempt
+func claimBatch() ([]models.OutboxEmail, error) {
+	var emails []models.OutboxEmail
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []models.OutboxEmailStatus{models.EmailPending, models.EmailSending}, time.Now()).
+			Order("next_attempt_at").
+			Limit(batchSize).
+			Find(&emails).Error; err != nil {
+			return err
+		}
+		if len(emails) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(emails))
+		for i := range emails {
+			emails[i].Attempts++
+			ids[i] = emails[i].ID
+		}
+		return tx.Model(&models.OutboxEmail{}).Where("id IN ?", ids).Updates(map[string]any{
+			"status":          models.EmailSending,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": time.Now().Add(sendLease),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+// retryDelay doubles from 1 minute: 1, 2, 4, 8, 16...
+func retryDelay(attempts int) time.Duration {
+	return time.Minute << (attempts - 1)
+}
+
+func send(cfg smtpConfig, email *models.OutboxEmail) error {
+	subject, body, err := render(email.Template, email.Data)
+	if err != nil {
+		return err
+	}
+	return cfg.send(email.To, subject, body)
+}
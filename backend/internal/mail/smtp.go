@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// smtpConfig comes from SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME,
+// SMTP_PASSWORD and SMTP_FROM. Without a username no authentication is used,
+// which is what local catchers like MailHog expect.
+type smtpConfig struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func configFromEnv() (smtpConfig, bool) {
+	cfg := smtpConfig{
+		host:     os.Getenv("SMTP_HOST"),
+		port:     os.Getenv("SMTP_PORT"),
+		username: os.Getenv("SMTP_USERNAME"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		from:     os.Getenv("SMTP_FROM"),
+	}
+	if cfg.port == "" {
+		cfg.port = "587"
+	}
+	if cfg.from == "" {
+		cfg.from = "Sanca Brechó <nao-responda@sancabrecho.com.br>"
+	}
+	return cfg, cfg.host != ""
+}
+
+func (cfg smtpConfig) addr() string {
+	return net.JoinHostPort(cfg.host, cfg.port)
+}
+
+func (cfg smtpConfig) send(to, subject, htmlBody string) error {
+	from, err := mail.ParseAddress(cfg.from)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+
+	var auth smtp.Auth
+	if cfg.username != "" {
+		auth = smtp.PlainAuth("", cfg.username, cfg.password, cfg.host)
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + from.String() + "\r\n")
+	msg.WriteString("To: " + to + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("Message-ID: <" + uuid.NewString() + "@" + cfg.host + ">\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(htmlBody, "\n", "\r\n"))
+
+	return smtp.SendMail(cfg.addr(), auth, from.Address, []string{to}, []byte(msg.String()))
+}
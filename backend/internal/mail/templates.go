@@ -0,0 +1,118 @@
+package mail
+
+import (
+	"api/internal/models"
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Template names. Notifications use their type as template name.
+const (
+	TemplateWelcome          = "welcome"
+	TemplateVerificationCode = "verification_code"
+)
+
+// templateFiles maps each template to its file under templates/
+var templateFiles = map[string]string{
+	TemplateWelcome:                              "welcome.html",
+	TemplateVerificationCode:                     "verification_code.html",
+	string(models.NotificationListingInterest):   "listing_interest.html",
+	string(models.NotificationOfferReceived):     "offer_received.html",
+	string(models.NotificationOfferCountered):    "offer_countered.html",
+	string(models.NotificationOfferAnswered):     "offer_answered.html",
+	string(models.NotificationSaleProposed):      "sale_proposed.html",
+	string(models.NotificationSaleConfirmed):     "sale_confirmed.html",
+	string(models.NotificationSaleClosed):        "sale_closed.html",
+	string(models.NotificationReviewReceived):    "review_received.html",
+	string(models.NotificationReportUpdated):     "report_updated.html",
+	string(models.NotificationListingRemoved):    "listing_removed.html",
+	string(models.NotificationListingHidden):     "listing_hidden.html",
+	string(models.NotificationModerationWarning): "moderation_warning.html",
+	string(models.NotificationAccountSuspended):  "account_suspended.html",
+	string(models.NotificationAccountBanned):     "account_banned.html",
+}
+
+var funcs = template.FuncMap{
+	// price formats a value as BRL, e.g. R$ 1.234,50
+	"price": func(v any) string {
+		var f float64
+		switch n := v.(type) {
+		case float64:
+			f = n
+		case int:
+			f = float64(n)
+		}
+		s := fmt.Sprintf("%.2f", f)
+		intPart, dec := s[:len(s)-3], s[len(s)-2:]
+		var b strings.Builder
+		for i, r := range intPart {
+			if i > 0 && (len(intPart)-i)%3 == 0 {
+				b.WriteByte('.')
+			}
+			b.WriteRune(r)
+		}
+		return "R$ " + b.String() + "," + dec
+	},
+	// date formats an RFC 3339 timestamp as dd/mm/yyyy in São Paulo time
+	"date": func(v any) string {
+		s, _ := v.(string)
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return s
+		}
+		if loc, err := time.LoadLocation("America/Sao_Paulo"); err == nil {
+			t = t.In(loc)
+		}
+		return t.Format("02/01/2006")
+	},
+}
+
+var templates = parseTemplates()
+
+func parseTemplates() map[string]*template.Template {
+	parsed := make(map[string]*template.Template, len(templateFiles))
+	for name, file := range templateFiles {
+		parsed[name] = template.Must(template.New(name).Funcs(funcs).
+			ParseFS(templateFS, "templates/layout.html", "templates/"+file))
+	}
+	return parsed
+}
+
+// HasTemplate reports whether name is a known template
+func HasTemplate(name string) bool {
+	_, ok := templates[name]
+	return ok
+}
+
+// render returns the subject and the HTML body of an email
+func render(name string, data models.JSONMap) (string, string, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return "", "", fmt.Errorf("unknown email template %q", name)
+	}
+
+	vars := make(map[string]any, len(data)+1)
+	for k, v := range data {
+		vars[k] = v
+	}
+	vars["frontend_url"] = strings.TrimRight(os.Getenv("FRONTEND_URL"), "/")
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", vars); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "layout", vars); err != nil {
+		return "", "", err
+	}
+	// The subject is plain text, undo the HTML escaping of the values
+	return html.UnescapeString(strings.TrimSpace(subject.String())), body.String(), nil
+}
@@ -1,54 +1,28 @@
 package middleware
 
 import (
-	"api/internal/config"
+	"api/internal/apperror"
 	"api/internal/models"
-	"api/internal/repository"
-	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware verifies the Bearer token and fetches the user.
-// It expects an "Authorization" header in the format "Bearer <token>".
-func AdminAuth(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing token"})
-		return
+// Require returns a middleware letting through the users whose role grants
+// permission. It authenticates the request unless Auth already did.
+func Require(permission models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if current, ok := c.Get("currentUser"); ok {
+			user = current.(models.User)
+		} else if user, ok = authenticate(c); !ok {
+			return
+		}
+
+		if !user.Role.Can(permission) {
+			apperror.Abort(c, apperror.ErrMissingPermission.With("permission", permission))
+			return
+		}
+
+		c.Next()
 	}
-
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
-		return
-	}
-
-	idToken := parts[1]
-
-	// Verify the ID token with Firebase
-	ctx := c.Request.Context()
-	token, err := config.AuthClient.VerifyIDToken(ctx, idToken)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Upsert the user
-	var user models.User
-	err = repository.DB.Where("id = ?", token.UID).Find(&user).Error
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
-	}
-
-	if user.Role != models.RoleAdmin {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "You are not admin"})
-		return
-	}
-
-	c.Set("currentUser", user)
-
-	c.Next()
 }
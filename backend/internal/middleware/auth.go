@@ -1,28 +1,44 @@
 package middleware
 
 import (
+	"api/internal/apperror"
 	"api/internal/config"
 	"api/internal/models"
+	"api/internal/moderation"
 	"api/internal/repository"
-	"net/http"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // AuthMiddleware verifies the Bearer token and fetches the user.
 // It expects an "Authorization" header in the format "Bearer <token>".
 func Auth(c *gin.Context) {
+	if _, ok := authenticate(c); !ok {
+		return
+	}
+
+	c.Next()
+}
+
+// authenticate verifies the token, loads the user and sets currentUser,
+// aborting the request when it fails
+func authenticate(c *gin.Context) (models.User, bool) {
+	var user models.User
+
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing token"})
-		return
+		apperror.Abort(c, apperror.ErrMissingToken)
+		return user, false
 	}
 
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
-		return
+		apperror.Abort(c, apperror.ErrInvalidTokenFormat)
+		return user, false
 	}
 
 	idToken := parts[1]
@@ -31,19 +47,29 @@ func Auth(c *gin.Context) {
 	ctx := c.Request.Context()
 	token, err := config.AuthClient.VerifyIDToken(ctx, idToken)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
+		apperror.Abort(c, apperror.ErrInvalidToken.Wrap(err))
+		return user, false
 	}
 
-	// Upsert the user
-	var user models.User
-	err = repository.DB.Where("id = ?", token.UID).Find(&user).Error
+	err = repository.DB.Where("id = ?", token.UID).First(&user).Error
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apperror.Abort(c, apperror.ErrUserNotRegistered)
+			return user, false
+		}
+		apperror.Abort(c, err)
+		return user, false
+	}
+
+	// Banned and suspended users are logged out everywhere
+	if err := moderation.AccountError(user); err != nil {
+		apperror.Abort(c, err)
+		return user, false
 	}
 
 	c.Set("currentUser", user)
+	// Long-lived requests (the event stream) end when the token expires
+	c.Set("tokenExpiresAt", time.Unix(token.Expires, 0))
 
-	c.Next()
+	return user, true
 }
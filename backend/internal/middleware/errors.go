@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"api/internal/apperror"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Errors renders the error recorded by apperror.Abort as
+// {"error": message, "code": CODE, "details": {...}}, in the language of the
+// Accept-Language header. Errors that are not an *apperror.Error become
+// INTERNAL_ERROR, and causes of server errors are only logged.
+func Errors(c *gin.Context) {
+	c.Next()
+
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+
+	err := c.Errors.Last().Err
+	appErr := apperror.From(err)
+	if appErr.Status >= http.StatusInternalServerError {
+		log.Printf("❌ %s %s: %v", c.Request.Method, c.FullPath(), err)
+	}
+
+	body := gin.H{
+		"error": appErr.Message(apperror.Language(c.GetHeader("Accept-Language"))),
+		"code":  appErr.Code,
+	}
+	if details := appErr.Details(); details != nil {
+		body["details"] = details
+	}
+
+	c.JSON(appErr.Status, body)
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditUserDelete          = "user.delete"
+	AuditUserRoleUpdate      = "user.role_update"
+	AuditUserSuspend         = "user.suspend"
+	AuditUserUnsuspend       = "user.unsuspend"
+	AuditUserBan             = "user.ban"
+	AuditUserUnban           = "user.unban"
+	AuditUserVerify          = "user.verify"
+	AuditUserUnverify        = "user.unverify"
+	AuditListingDelete       = "listing.delete"
+	AuditListingStatusUpdate = "listing.status_update"
+	AuditCategoryCreate      = "category.create"
+	AuditCategoryUpdate      = "category.update"
+	AuditCategoryDelete      = "category.delete"
+	AuditInstitutionCreate   = "institution.create"
+	AuditInstitutionUpdate   = "institution.update"
+	AuditInstitutionDelete   = "institution.delete"
+	AuditReportStatusUpdate  = "report.status_update"
+	AuditReportAssign        = "report.assign"
+	AuditReviewHide          = "review.hide"
+	AuditReviewUnhide        = "review.unhide"
+	AuditStorageReconcile    = "storage.reconcile"
+)
+
+// Target types of the audit log
+const (
+	AuditTargetUser        = "user"
+	AuditTargetListing     = "listing"
+	AuditTargetCategory    = "category"
+	AuditTargetInstitution = "institution"
+	AuditTargetReport      = "report"
+	AuditTargetReview      = "review"
+	AuditTargetReconcile   = "reconcile_run"
+)
+
+// AdminAction is an entry of the append-only audit log of the admins. The
+// actor is a plain id with a copy of their name, so entries outlive accounts.
+type AdminAction struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ActorID    string    `json:"actor_id" gorm:"not null;index:idx_admin_action_actor_created"`
+	ActorName  string    `json:"actor_name"`
+	Action     string    `json:"action" gorm:"not null;index"`
+	TargetType string    `json:"target_type" gorm:"not null;index:idx_admin_action_target"`
+	TargetID   string    `json:"target_id" gorm:"not null;index:idx_admin_action_target"`
+	Before     JSONMap   `json:"before" gorm:"type:jsonb"`
+	After      JSONMap   `json:"after" gorm:"type:jsonb"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime;index;index:idx_admin_action_actor_created"`
+}
@@ -8,3 +8,14 @@ type Category struct {
 	Parent   *Category   `json:"parent" gorm:"foreignKey:ParentID;references:ID"`
 	Children []*Category `json:"children" gorm:"foreignKey:ParentID;references:ID"`
 }
+
+// CategoryTreeNode is a category in the nested hierarchy returned by /categories/tree
+type CategoryTreeNode struct {
+	ID                int                 `json:"id"`
+	Name              string              `json:"name"`
+	Icon              string              `json:"icon"`
+	ParentID          *int                `json:"parent_id"`
+	ListingCount      int64               `json:"listing_count"`       // active listings in this category
+	TotalListingCount int64               `json:"total_listing_count"` // active listings in this category and its descendants
+	Children          []*CategoryTreeNode `json:"children"`
+}
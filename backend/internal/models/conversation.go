@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Conversation is the message thread between a buyer and the seller of a listing
+type Conversation struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ListingID     *uuid.UUID `json:"listing_id" gorm:"type:uuid;uniqueIndex:idx_conversation_listing_buyer"` // nil once the listing is deleted
+	Listing       *Listing   `json:"listing" gorm:"foreignKey:ListingID;references:ID;constraint:OnDelete:SET NULL"`
+	ListingTitle  string     `json:"listing_title" gorm:"not null;default:''"` // kept when the listing is deleted
+	BuyerID       string     `json:"buyer_id" gorm:"not null;uniqueIndex:idx_conversation_listing_buyer;index"`
+	Buyer         User       `json:"buyer" gorm:"foreignKey:BuyerID;references:ID;constraint:OnDelete:CASCADE"`
+	SellerID      string     `json:"seller_id" gorm:"not null;index"`
+	Seller        User       `json:"seller" gorm:"foreignKey:SellerID;references:ID;constraint:OnDelete:CASCADE"`
+	Messages      []Message  `json:"-" gorm:"foreignKey:ConversationID;constraint:OnDelete:CASCADE"`
+	LastMessageAt *time.Time `json:"last_message_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	// Preenchidos pelo handler
+	Closed      bool     `json:"closed" gorm:"-"`  // anúncio vendido ou removido: só leitura
+	Blocked     bool     `json:"blocked" gorm:"-"` // um dos participantes bloqueou o outro
+	UnreadCount int64    `json:"unread_count" gorm:"-"`
+	LastMessage *Message `json:"last_message,omitempty" gorm:"-"`
+}
+
+// Message is a message of a conversation. ReadAt is set when the recipient reads it.
+type Message struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ConversationID uuid.UUID  `json:"conversation_id" gorm:"type:uuid;not null;index:idx_messages_conversation_created,priority:1"`
+	SenderID       string     `json:"sender_id" gorm:"not null"`
+	Sender         User       `json:"-" gorm:"foreignKey:SenderID;references:ID;constraint:OnDelete:CASCADE"`
+	Body           string     `json:"body" gorm:"type:text;not null"`
+	ReadAt         *time.Time `json:"read_at"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime;index:idx_messages_conversation_created,priority:2"`
+}
+
+// UserBlock means BlockerID no longer exchanges messages with BlockedID, in either direction
+type UserBlock struct {
+	BlockerID string    `json:"blocker_id" gorm:"primaryKey"`
+	Blocker   User      `json:"-" gorm:"foreignKey:BlockerID;references:ID;constraint:OnDelete:CASCADE"`
+	BlockedID string    `json:"blocked_id" gorm:"primaryKey;index"`
+	Blocked   User      `json:"blocked" gorm:"foreignKey:BlockedID;references:ID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
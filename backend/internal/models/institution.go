@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Institution is a higher education institution whose members can sign up.
+// Domains are the email domains of its members: "usp.br" matches only that
+// domain and "*.usp.br" any of its subdomains, like "icmc.usp.br".
+type Institution struct {
+	ID        int                 `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name      string              `json:"name" gorm:"not null"`
+	ShortName string              `json:"short_name" gorm:"not null"`
+	Domains   []InstitutionDomain `json:"domains" gorm:"foreignKey:InstitutionID;constraint:OnDelete:CASCADE"`
+	Campus    *string             `json:"campus"`
+	City      *string             `json:"city"`
+	Active    bool                `json:"active" gorm:"not null;default:true"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// InstitutionDomain is a domain pattern of an institution. The pattern is the
+// key, so two institutions can never claim the same one.
+type InstitutionDomain struct {
+	Pattern       string `gorm:"primaryKey"`
+	InstitutionID int    `gorm:"not null;index"`
+}
+
+// MarshalJSON sends a domain as its pattern
+func (d InstitutionDomain) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Pattern)
+}
+
+// NormalizeDomainPattern lowercases a domain pattern and checks it is either
+// a domain or "*." followed by a domain
+func NormalizeDomainPattern(pattern string) (string, bool) {
+	p := strings.ToLower(strings.TrimSpace(pattern))
+	domain := strings.TrimPrefix(p, "*.")
+	if domain == "" || !strings.Contains(domain, ".") || strings.ContainsAny(domain, "*@ /") ||
+		strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", false
+	}
+	return p, true
+}
+
+// MatchDomain reports whether domain belongs to the institution. The score
+// ranks the matches: an exact domain beats a wildcard, and a longer
+// wildcard beats a shorter one.
+func (i Institution) MatchDomain(domain string) (score int, ok bool) {
+	domain = strings.ToLower(domain)
+	for _, d := range i.Domains {
+		pattern := d.Pattern
+		switch {
+		case pattern == domain:
+			return 1 << 16, true
+		case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(domain, pattern[1:]):
+			if len(pattern) > score {
+				score, ok = len(pattern), true
+			}
+		}
+	}
+	return score, ok
+}
@@ -22,29 +22,37 @@ type Status string
 
 const (
 	Available Status = "available"
+	Reserved  Status = "reserved" // on hold for a buyer, by the seller or while a sale waits for confirmation
 	Sold      Status = "sold"
 	Deleted   Status = "deleted"
+	Hidden    Status = "hidden" // taken down by the moderation, only an admin can restore it
 )
 
+// ActiveStatuses are the statuses of listings still on offer, shown in the feeds
+var ActiveStatuses = []Status{Available, Reserved}
+
 type Listing struct {
-	ID               uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	UserID           string    `json:"user_id" gorm:"not null"` // string, compatível com ID do Firebase
-	User             User      `json:"user" gorm:"foreignKey:UserID;references:ID"`
-	CategoryID       int       `json:"category_id" gorm:"not null"`
-	Category         Category  `json:"category" gorm:"foreignKey:CategoryID;references:ID"`
-	Title            string    `json:"title" gorm:"not null"`
-	Keywords         string    `json:"keywords" gorm:"not null"` // sequencia de palavra chaves separadas por espaço (string paddrao. ex: celular iphone telefone)
-	Slug             string    `json:"slug" gorm:"not null;uniqueIndex"`
-	Description      string    `json:"description" gorm:"not null"`
-	Price            float64   `json:"price" gorm:"not null"`
-	Condition        Condition `json:"condition" gorm:"type:condition_enum;not null"`
-	IsNegotiable     bool      `json:"is_negotiable" gorm:"not null"`
-	SellerCanDeliver bool      `json:"seller_can_deliver" gorm:"not null"`
-	Location         string    `json:"location" gorm:"not null"`
-	Status           Status    `json:"status" gorm:"type:status_enum;not null;default:available"`
-	Sale             *Sale     `json:"sale"`
-	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt        time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID           string     `json:"user_id" gorm:"not null"` // string, compatível com ID do Firebase
+	User             User       `json:"user" gorm:"foreignKey:UserID;references:ID"`
+	CategoryID       int        `json:"category_id" gorm:"not null"`
+	Category         Category   `json:"category" gorm:"foreignKey:CategoryID;references:ID"`
+	Title            string     `json:"title" gorm:"not null"`
+	Keywords         string     `json:"keywords" gorm:"not null"` // sequencia de palavra chaves separadas por espaço (string paddrao. ex: celular iphone telefone)
+	Slug             string     `json:"slug" gorm:"not null;uniqueIndex"`
+	Description      string     `json:"description" gorm:"not null"`
+	Price            float64    `json:"price" gorm:"not null"`
+	Condition        Condition  `json:"condition" gorm:"type:condition_enum;not null"`
+	IsNegotiable     bool       `json:"is_negotiable" gorm:"not null"`
+	SellerCanDeliver bool       `json:"seller_can_deliver" gorm:"not null"`
+	Location         string     `json:"location" gorm:"not null"`
+	Status           Status     `json:"status" gorm:"type:status_enum;not null;default:available"`
+	ReservedForID    *string    `json:"-"`                                  // optional, buyer the listing is on hold for
+	ReservedFor      *string    `json:"reserved_for_id,omitempty" gorm:"-"` // ReservedForID, set only for the seller and that buyer
+	ReservedUntil    *time.Time `json:"reserved_until"`                     // the reservation is released after this
+	Sale             *Sale      `json:"sale"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 func (l *Listing) BeforeCreate(tx *gorm.DB) (err error) {
@@ -3,10 +3,26 @@ package models
 import "github.com/google/uuid"
 
 type ListingImage struct {
-	ID        uuid.UUID `json:"id" gorm:"type: uuid;default:uuid_generate_v4();primary_key"`
-	ListingID uuid.UUID `json:"listing_id" gorm:"type:uuid;not null"`
-	Listing   Listing   `json:"listing" gorm:"foreignKey:ListingID;references:ID"`
-	Src       string    `json:"src" gorm:"not null"`
-	Order     int       `json:"order" gorm:"default:0"`
-	Key       string    `json:"key" gorm:"not null"`
+	ID        uuid.UUID             `json:"id" gorm:"type: uuid;default:uuid_generate_v4();primary_key"`
+	ListingID uuid.UUID             `json:"listing_id" gorm:"type:uuid;not null"`
+	Listing   Listing               `json:"listing" gorm:"foreignKey:ListingID;references:ID"`
+	Src       string                `json:"src" gorm:"not null"`
+	Order     int                   `json:"order" gorm:"default:0"`
+	Key       string                `json:"key" gorm:"not null"`
+	Variants  []ListingImageVariant `json:"variants,omitempty" gorm:"foreignKey:ListingImageID;constraint:OnDelete:CASCADE"`
+}
+
+// ListingImageVariant is a re-encoded size (thumbnail, card, full) of an image
+// uploaded through the server-side pipeline. Src/Key of the parent image point
+// at the "full" variant.
+type ListingImageVariant struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ListingImageID uuid.UUID `json:"listing_image_id" gorm:"type:uuid;not null;uniqueIndex:idx_listing_image_variant"`
+	Name           string    `json:"name" gorm:"not null;uniqueIndex:idx_listing_image_variant"`
+	Src            string    `json:"src" gorm:"not null"`
+	Key            string    `json:"key" gorm:"not null"`
+	Width          int       `json:"width" gorm:"not null"`
+	Height         int       `json:"height" gorm:"not null"`
+	Size           int64     `json:"size" gorm:"not null"`
+	ContentType    string    `json:"content_type" gorm:"not null"`
 }
@@ -10,4 +10,6 @@ type SellerMetrics struct {
 	TotalListingsCount  int64      `json:"total_listings_count"`
 	TotalFavoritesCount int64      `json:"total_favorites_count"`
 	MemberSince         *time.Time `json:"member_since"`
+	Reputation          Reputation `json:"reputation"`       // as seller, from the buyers' reviews
+	BuyerReputation     Reputation `json:"buyer_reputation"` // as buyer, from the sellers' reviews
 }
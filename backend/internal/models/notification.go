@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationType string
+
+const (
+	NotificationListingInterest NotificationType = "listing.interest" // a buyer started a conversation on a listing of the user
+	NotificationOfferReceived   NotificationType = "offer.received"   // a buyer made or countered an offer on a listing of the user
+	NotificationOfferCountered  NotificationType = "offer.countered"  // the seller countered an offer of the user
+	NotificationOfferAnswered   NotificationType = "offer.answered"   // a proposal of the user was accepted or rejected
+	NotificationSaleProposed    NotificationType = "sale.proposed"    // a seller registered a sale with the user as buyer, to confirm
+	NotificationSaleConfirmed   NotificationType = "sale.confirmed"   // the buyer confirmed a sale of the user
+	NotificationSaleClosed      NotificationType = "sale.closed"      // a pending sale was rejected, cancelled or expired
+	NotificationReviewReceived  NotificationType = "review.received"  // the other party of a sale reviewed the user
+	NotificationReportUpdated   NotificationType = "report.updated"   // an admin changed the status of a report of the user
+	NotificationListingRemoved  NotificationType = "listing.removed"  // an admin removed a listing of the user
+)
+
+// Moderation notices. They are left out of NotificationTypes so they can't be turned off.
+const (
+	NotificationListingHidden     NotificationType = "listing.hidden"     // a listing of the user was hidden after a report
+	NotificationModerationWarning NotificationType = "moderation.warning" // an admin warned the user after a report
+	NotificationAccountSuspended  NotificationType = "account.suspended"  // the account of the user was suspended
+	NotificationAccountBanned     NotificationType = "account.banned"     // the account of the user was banned
+)
+
+// NotificationTypes lists every type a user can set preferences for
+var NotificationTypes = []NotificationType{
+	NotificationListingInterest,
+	NotificationOfferReceived,
+	NotificationOfferCountered,
+	NotificationOfferAnswered,
+	NotificationSaleProposed,
+	NotificationSaleConfirmed,
+	NotificationSaleClosed,
+	NotificationReviewReceived,
+	NotificationReportUpdated,
+	NotificationListingRemoved,
+}
+
+type NotificationChannel string
+
+const (
+	ChannelInApp NotificationChannel = "in_app"
+	ChannelEmail NotificationChannel = "email"
+)
+
+var NotificationChannels = []NotificationChannel{ChannelInApp, ChannelEmail}
+
+func (t NotificationType) Valid() bool {
+	for _, v := range NotificationTypes {
+		if t == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (ch NotificationChannel) Valid() bool {
+	for _, v := range NotificationChannels {
+		if ch == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification is shown in the notification center of a user. Data holds the
+// ids and names the client needs to render and link it, depending on the type.
+type Notification struct {
+	ID        uuid.UUID        `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID    string           `json:"user_id" gorm:"not null;index:idx_notification_user_created,priority:1"`
+	User      User             `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Type      NotificationType `json:"type" gorm:"not null"`
+	Data      JSONMap          `json:"data" gorm:"type:jsonb;not null;default:'{}'"`
+	ReadAt    *time.Time       `json:"read_at"`
+	CreatedAt time.Time        `json:"created_at" gorm:"index:idx_notification_user_created,priority:2,sort:desc"`
+}
+
+// NotificationPreference opts a user in or out of a type on a channel.
+// Without a row, the notification is sent.
+type NotificationPreference struct {
+	UserID    string              `json:"-" gorm:"primaryKey"`
+	User      User                `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Type      NotificationType    `json:"type" gorm:"primaryKey"`
+	Channel   NotificationChannel `json:"channel" gorm:"primaryKey"`
+	Enabled   bool                `json:"enabled" gorm:"not null"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
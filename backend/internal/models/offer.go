@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OfferStatus string
+
+const (
+	OfferOpen      OfferStatus = "open"      // waiting for the party that did not make the last proposal
+	OfferAccepted  OfferStatus = "accepted"  // the last proposal was accepted
+	OfferRejected  OfferStatus = "rejected"  // rejected, or closed because the listing was sold
+	OfferWithdrawn OfferStatus = "withdrawn" // withdrawn by the buyer
+	OfferExpired   OfferStatus = "expired"   // nobody answered in time
+)
+
+type OfferParty string
+
+const (
+	OfferByBuyer  OfferParty = "buyer"
+	OfferBySeller OfferParty = "seller"
+)
+
+// Offer is a price negotiation between a buyer and the seller of a negotiable
+// listing. Amount is the last proposal: the buyer opens with an offer and each
+// counter-offer replaces it, until one side accepts or rejects the other's.
+// A buyer has at most one open offer per listing.
+type Offer struct {
+	ID         uuid.UUID   `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ListingID  uuid.UUID   `json:"listing_id" gorm:"type:uuid;not null;index"`
+	Listing    Listing     `json:"listing" gorm:"foreignKey:ListingID;references:ID;constraint:OnDelete:CASCADE"`
+	BuyerID    string      `json:"buyer_id" gorm:"not null;index"`
+	Buyer      User        `json:"buyer" gorm:"foreignKey:BuyerID;references:ID;constraint:OnDelete:CASCADE"`
+	Amount     float64     `json:"amount" gorm:"not null"`
+	ProposedBy OfferParty  `json:"proposed_by" gorm:"not null"`
+	Rounds     int         `json:"rounds" gorm:"not null;default:1"` // proposals made so far, counter-offers included
+	Status     OfferStatus `json:"status" gorm:"not null;default:open"`
+	ExpiresAt  time.Time   `json:"expires_at" gorm:"not null"`
+	AnsweredAt *time.Time  `json:"answered_at"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OutboxEmailStatus string
+
+const (
+	EmailPending OutboxEmailStatus = "pending"
+	EmailSending OutboxEmailStatus = "sending" // claimed by a worker until NextAttemptAt
+	EmailSent    OutboxEmailStatus = "sent"
+	EmailFailed  OutboxEmailStatus = "failed" // gave up after the last attempt
+)
+
+// OutboxEmail is an email waiting to be (or already) sent by the mail worker.
+// It is written in the same transaction as the change that caused it.
+type OutboxEmail struct {
+	ID            uuid.UUID         `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	To            string            `json:"to" gorm:"not null"`
+	Template      string            `json:"template" gorm:"not null"`
+	Data          JSONMap           `json:"data" gorm:"type:jsonb;not null;default:'{}'"`
+	Status        OutboxEmailStatus `json:"status" gorm:"not null;default:'pending';index:idx_outbox_email_due,priority:1"`
+	Attempts      int               `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt time.Time         `json:"next_attempt_at" gorm:"not null;index:idx_outbox_email_due,priority:2"`
+	LastError     *string           `json:"last_error"`
+	SentAt        *time.Time        `json:"sent_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PendingUpload is a presigned storage key that its uploader has not attached
+// to a listing yet. Only the uploader can turn it into a ListingImage, and
+// only on the listing it was presigned for (when one was given).
+type PendingUpload struct {
+	Key         string     `json:"key" gorm:"primaryKey"`
+	UserID      string     `json:"user_id" gorm:"not null;index"`
+	User        User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	ListingID   *uuid.UUID `json:"listing_id" gorm:"type:uuid"`
+	ContentType string     `json:"content_type" gorm:"not null"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,67 @@
+package models
+
+// Permission is a privileged capability, checked per route by middleware.Require
+type Permission string
+
+const (
+	PermStatsRead          Permission = "stats:read"
+	PermAuditRead          Permission = "audit:read"
+	PermReportsRead        Permission = "reports:read"
+	PermReportsResolve     Permission = "reports:resolve" // assign, annotate and resolve reports, applying their actions
+	PermReviewsModerate    Permission = "reviews:moderate"
+	PermListingsModerate   Permission = "listings:moderate" // see every listing, change its status or delete it
+	PermUsersRead          Permission = "users:read"
+	PermUsersSuspend       Permission = "users:suspend" // suspend and ban
+	PermUsersDelete        Permission = "users:delete"
+	PermUsersVerify        Permission = "users:verify" // grant and revoke verification
+	PermRolesGrant         Permission = "roles:grant"
+	PermCategoriesManage   Permission = "categories:manage"
+	PermInstitutionsManage Permission = "institutions:manage" // allowed institutions and their email domains
+	PermStorageManage      Permission = "storage:manage"
+)
+
+// rolePermissions lists what each role can do, regular users have no permission
+var rolePermissions = map[UserRole][]Permission{
+	RoleModerator: {
+		PermStatsRead, PermReportsRead, PermReportsResolve, PermReviewsModerate,
+		PermListingsModerate, PermUsersRead, PermUsersSuspend, PermUsersVerify,
+	},
+	RoleCategoryManager: {
+		PermStatsRead, PermCategoriesManage,
+	},
+	RoleAdmin: {
+		PermStatsRead, PermAuditRead, PermReportsRead, PermReportsResolve, PermReviewsModerate,
+		PermListingsModerate, PermUsersRead, PermUsersSuspend, PermUsersVerify, PermUsersDelete,
+		PermCategoriesManage, PermInstitutionsManage, PermStorageManage,
+	},
+	RoleSuperadmin: {
+		PermStatsRead, PermAuditRead, PermReportsRead, PermReportsResolve, PermReviewsModerate,
+		PermListingsModerate, PermUsersRead, PermUsersSuspend, PermUsersVerify, PermUsersDelete,
+		PermCategoriesManage, PermInstitutionsManage, PermStorageManage, PermRolesGrant,
+	},
+}
+
+// Valid reports whether r is a known role
+func (r UserRole) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok || r == RoleUser
+}
+
+// Permissions returns the permissions of the role
+func (r UserRole) Permissions() []Permission {
+	perms := rolePermissions[r]
+	if perms == nil {
+		return []Permission{}
+	}
+	return perms
+}
+
+// Can reports whether the role grants p
+func (r UserRole) Can(p Permission) bool {
+	for _, perm := range rolePermissions[r] {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
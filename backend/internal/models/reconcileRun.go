@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReconcileRunStatus string
+
+const (
+	ReconcileRunning   ReconcileRunStatus = "running"
+	ReconcileCompleted ReconcileRunStatus = "completed"
+	ReconcileFailed    ReconcileRunStatus = "failed"
+)
+
+// ReconcileRun is the report of one run of the storage/database image reconciler
+type ReconcileRun struct {
+	ID            uuid.UUID          `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Trigger       string             `json:"trigger" gorm:"not null"` // "cron" or "manual"
+	TriggeredByID *string            `json:"triggered_by_id"`
+	DryRun        bool               `json:"dry_run" gorm:"not null"`
+	GracePeriod   string             `json:"grace_period" gorm:"not null"`
+	Status        ReconcileRunStatus `json:"status" gorm:"not null"`
+	StartedAt     time.Time          `json:"started_at" gorm:"not null"`
+	FinishedAt    *time.Time         `json:"finished_at"`
+
+	ObjectsScanned int `json:"objects_scanned"`
+	ImagesScanned  int `json:"images_scanned"`
+	// Objects not referenced by any image, variant or pending upload, older than the grace period
+	OrphanObjects  StringList `json:"orphan_objects" gorm:"type:jsonb;not null;default:'[]'"`
+	DeletedObjects int        `json:"deleted_objects"`
+	// Images whose object is missing from the storage
+	MissingImages StringList `json:"missing_images" gorm:"type:jsonb;not null;default:'[]'"`
+	DeletedImages int        `json:"deleted_images"`
+	// Objects skipped because they are younger than the grace period
+	SkippedRecent int        `json:"skipped_recent"`
+	Errors        StringList `json:"errors" gorm:"type:jsonb;not null;default:'[]'"`
+}
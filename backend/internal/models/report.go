@@ -28,18 +28,73 @@ type ReportTargetType string
 const (
 	TargetTypeProduct ReportTargetType = "product"
 	TargetTypeUser    ReportTargetType = "user"
+	TargetTypeReview  ReportTargetType = "review"
 )
 
+func (t ReportTargetType) Valid() bool {
+	switch t {
+	case TargetTypeProduct, TargetTypeUser, TargetTypeReview:
+		return true
+	}
+	return false
+}
+
+// ReportAction is the enforcement an admin applies when resolving a report
+type ReportAction string
+
+const (
+	ActionHideListing ReportAction = "hide_listing" // only for product reports
+	ActionWarnUser    ReportAction = "warn_user"
+	ActionSuspendUser ReportAction = "suspend_user"
+)
+
+func (a ReportAction) Valid() bool {
+	switch a {
+	case ActionHideListing, ActionWarnUser, ActionSuspendUser:
+		return true
+	}
+	return false
+}
+
 type Report struct {
-	ID         uuid.UUID        `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
-	ReporterID string           `json:"reporter_id" gorm:"not null"`
-	Reporter   User             `json:"reporter" gorm:"foreignKey:ReporterID;references:ID"`
-	TargetType ReportTargetType `gorm:"type:report_target_type_enum;not null" json:"target_type"`
-	TargetID   string           `json:"target_id" gorm:"not null"`
-	Reason     ReportReason     `gorm:"type:report_reason_enum;not null" json:"reason"`
-	Details    string           `gorm:"type:text" json:"details,omitempty"`
-	Status     ReportStatus     `gorm:"type:report_status_enum;default:'open'" json:"status"`
-	ResolvedAt *time.Time       `json:"resolved_at,omitempty"`
-	CreatedAt  time.Time        `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt  time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+	ID           uuid.UUID            `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	ReporterID   string               `json:"reporter_id" gorm:"not null"`
+	Reporter     User                 `json:"reporter" gorm:"foreignKey:ReporterID;references:ID"`
+	TargetType   ReportTargetType     `gorm:"type:report_target_type_enum;not null" json:"target_type"`
+	TargetID     string               `json:"target_id" gorm:"not null"`
+	Reason       ReportReason         `gorm:"type:report_reason_enum;not null" json:"reason"`
+	Details      string               `gorm:"type:text" json:"details,omitempty"`
+	Status       ReportStatus         `gorm:"type:report_status_enum;default:'open'" json:"status"`
+	ResolvedAt   *time.Time           `json:"resolved_at,omitempty"`
+	ResolvedByID *string              `json:"resolved_by_id,omitempty"`
+	AssigneeID   *string              `json:"assignee_id,omitempty" gorm:"index"`
+	Assignee     *User                `json:"assignee,omitempty" gorm:"foreignKey:AssigneeID;references:ID;constraint:OnDelete:SET NULL"`
+	Action       *ReportAction        `json:"action,omitempty"`
+	ActionData   JSONMap              `json:"action_data,omitempty" gorm:"type:jsonb"` // details of the action: target user or listing, days, message
+	Notes        []ReportNote         `json:"notes,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	History      []ReportStatusChange `json:"history,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt    time.Time            `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time            `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// ReportNote is an internal note of the admins on a report
+type ReportNote struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ReportID  uuid.UUID `json:"report_id" gorm:"type:uuid;not null;index"`
+	AuthorID  *string   `json:"author_id"`
+	Author    *User     `json:"author,omitempty" gorm:"foreignKey:AuthorID;references:ID;constraint:OnDelete:SET NULL"`
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// ReportStatusChange records a status transition of a report and the action applied with it
+type ReportStatusChange struct {
+	ID          uuid.UUID     `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ReportID    uuid.UUID     `json:"report_id" gorm:"type:uuid;not null;index"`
+	From        ReportStatus  `json:"from" gorm:"column:from_status;type:report_status_enum;not null"`
+	To          ReportStatus  `json:"to" gorm:"column:to_status;type:report_status_enum;not null"`
+	Action      *ReportAction `json:"action,omitempty"`
+	ChangedByID *string       `json:"changed_by_id"`
+	ChangedBy   *User         `json:"changed_by,omitempty" gorm:"foreignKey:ChangedByID;references:ID;constraint:OnDelete:SET NULL"`
+	CreatedAt   time.Time     `json:"created_at" gorm:"autoCreateTime"`
 }
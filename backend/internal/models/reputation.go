@@ -0,0 +1,16 @@
+package models
+
+// Reputation aggregates the reviews a user received in one role
+type Reputation struct {
+	Average      float64         `json:"average"`
+	Count        int64           `json:"count"`
+	Distribution map[int]int64   `json:"distribution"` // reviews per rating, 1 to 5
+	Trend        ReputationTrend `json:"trend"`
+}
+
+// ReputationTrend compares the average of the last 90 days with the 90 days before
+type ReputationTrend struct {
+	RecentAverage   *float64 `json:"recent_average"`
+	PreviousAverage *float64 `json:"previous_average"`
+	Direction       string   `json:"direction"` // "up", "down", "stable" or "unknown" without reviews in both periods
+}
@@ -6,11 +6,34 @@ import (
 	"github.com/google/uuid"
 )
 
+// ReviewRole is the side of the sale the reviewer was on
+type ReviewRole string
+
+const (
+	ReviewByBuyer  ReviewRole = "buyer"  // the buyer reviews the seller
+	ReviewBySeller ReviewRole = "seller" // the seller reviews the buyer
+)
+
+// Review of a confirmed sale. Each side can review the other once, and the
+// reviewed user can post one public reply. Reviews hidden by an admin are kept
+// with the reason but left out of the public lists and of the reputation.
 type Review struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	SaleID    uuid.UUID `json:"sale_id" gorm:"not null;unique"`
-	Sale      Sale      `json:"sale" gorm:"foreignKey:SaleID"`
-	Rating    int       `json:"rating" gorm:"not null"`
-	Comment   string    `json:"comment"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	SaleID       uuid.UUID  `json:"sale_id" gorm:"not null;uniqueIndex:idx_review_sale_reviewer"`
+	Sale         Sale       `json:"sale" gorm:"foreignKey:SaleID"`
+	ReviewerID   string     `json:"reviewer_id" gorm:"uniqueIndex:idx_review_sale_reviewer"` // always set, nullable so the column could be added to old rows
+	Reviewer     User       `json:"reviewer" gorm:"foreignKey:ReviewerID;references:ID"`
+	ReviewedID   string     `json:"reviewed_id" gorm:"index"`
+	Reviewed     User       `json:"reviewed" gorm:"foreignKey:ReviewedID;references:ID"`
+	Role         ReviewRole `json:"role" gorm:"not null;default:buyer"` // reviews from before seller reviews are by buyers
+	Rating       int        `json:"rating" gorm:"not null"`
+	Comment      string     `json:"comment"`
+	Reply        *string    `json:"reply"`
+	RepliedAt    *time.Time `json:"replied_at"`
+	EditedAt     *time.Time `json:"edited_at"`
+	HiddenAt     *time.Time `json:"hidden_at,omitempty" gorm:"index"`
+	HiddenByID   *string    `json:"hidden_by_id,omitempty"`
+	HiddenBy     *User      `json:"hidden_by,omitempty" gorm:"foreignKey:HiddenByID;references:ID;constraint:OnDelete:SET NULL"`
+	HiddenReason *string    `json:"hidden_reason,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
 }
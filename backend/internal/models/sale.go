@@ -6,15 +6,32 @@ import (
 	"github.com/google/uuid"
 )
 
+type SaleStatus string
+
+const (
+	SalePending   SaleStatus = "pending"   // proposed by the seller, waiting for the buyer
+	SaleConfirmed SaleStatus = "confirmed" // accepted by the buyer, or sold outside the platform
+	SaleRejected  SaleStatus = "rejected"  // rejected by the buyer
+	SaleCancelled SaleStatus = "cancelled" // withdrawn by the seller
+	SaleExpired   SaleStatus = "expired"   // the buyer did not answer in time
+)
+
+// Sale of a listing. The seller proposes it to a buyer, who has to confirm it;
+// only confirmed sales count as sold items and can be reviewed. A listing has
+// at most one pending or confirmed sale.
 type Sale struct {
-	ID         uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	ListingID  uuid.UUID `json:"listing_id" gorm:"not null;unique"`
-	Listing    Listing   `json:"listing"`
-	SellerID   string    `json:"seller_id" gorm:"not null"`
-	Seller     User      `json:"seller" gorm:"foreignKey:SellerID;references:ID"`
-	BuyerID    *string   `json:"buyer_id"`
-	Buyer      User      `json:"buyer" gorm:"foreignKey:BuyerID;references:ID"`
-	SoldAt     time.Time `json:"sold_at" gorm:"not null;autoCreateTime"`
-	FinalPrice float64   `json:"final_price"`
-	Review     *Review   `json:"review,omitempty"`
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ListingID   uuid.UUID  `json:"listing_id" gorm:"not null;index"`
+	Listing     Listing    `json:"listing"`
+	SellerID    string     `json:"seller_id" gorm:"not null"`
+	Seller      User       `json:"seller" gorm:"foreignKey:SellerID;references:ID"`
+	BuyerID     *string    `json:"buyer_id"`
+	Buyer       User       `json:"buyer" gorm:"foreignKey:BuyerID;references:ID"`
+	Status      SaleStatus `json:"status" gorm:"not null;default:confirmed"` // sales from before the confirmation step are confirmed
+	ExpiresAt   *time.Time `json:"expires_at"`                               // deadline of a pending sale
+	RespondedAt *time.Time `json:"responded_at"`
+	SoldAt      time.Time  `json:"sold_at" gorm:"not null;autoCreateTime"` // proposal time, then confirmation time
+	FinalPrice  float64    `json:"final_price"`
+	OfferID     *uuid.UUID `json:"offer_id" gorm:"type:uuid"` // accepted offer the price came from
+	Reviews     []Review   `json:"reviews,omitempty"`         // by the buyer and by the seller
 }
@@ -10,26 +10,50 @@ import (
 
 type UserRole string
 
+// Roles. Staff roles get their permissions from rolePermissions.
 const (
-	RoleUser  UserRole = "user"
-	RoleAdmin UserRole = "admin"
+	RoleUser            UserRole = "user"
+	RoleModerator       UserRole = "moderator"        // triages reports and moderates content
+	RoleCategoryManager UserRole = "category_manager" // manages the category tree
+	RoleAdmin           UserRole = "admin"            // everything but granting roles
+	RoleSuperadmin      UserRole = "superadmin"       // everything, there is always at least one
 )
 
 type User struct {
-	ID            string    `gorm:"primary_key"` // UUID firebase
-	DisplayName   string    `json:"display_name" gorm:"not null"`
-	Slug          string    `json:"slug" gorm:"uniqueIndex"`
-	Email         string    `json:"email" gorm:"not null;uniqueIndex"`
-	PhotoURL      *string   `json:"photo_url"`
-	University    *string   `json:"university"`
-	Whatsapp      *string   `json:"whatsapp"`
-	Telegram      *string   `json:"telegram"`
-	Verified      bool      `json:"verified" gorm:"default:false"`
-	Role          UserRole  `json:"role" gorm:"default:user"`
-	SalesAsBuyer  []Sale    `json:"-" gorm:"foreignKey:SellerID"`
-	SalesAsSeller []Sale    `json:"-" gorm:"foreignKey:BuyerID"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID                    string              `gorm:"primary_key"` // UUID firebase
+	DisplayName           string              `json:"display_name" gorm:"not null"`
+	Slug                  string              `json:"slug" gorm:"uniqueIndex"`
+	Email                 string              `json:"email" gorm:"not null;uniqueIndex"`
+	PhotoURL              *string             `json:"photo_url"`
+	University            *string             `json:"university" gorm:"->"` // name of the institution, kept in sync with InstitutionID by the database
+	InstitutionID         *int                `json:"institution_id" gorm:"index"`
+	Institution           *Institution        `json:"institution,omitempty" gorm:"foreignKey:InstitutionID;references:ID;constraint:OnDelete:SET NULL"`
+	Whatsapp              *string             `json:"whatsapp"`
+	Telegram              *string             `json:"telegram"`
+	Verified              bool                `json:"verified" gorm:"default:false"` // set by the verification flow only
+	VerificationMethod    *VerificationMethod `json:"verification_method,omitempty"`
+	VerifiedAt            *time.Time          `json:"verified_at,omitempty"`
+	VerificationRevokedAt *time.Time          `json:"verification_revoked_at,omitempty"` // set by an admin, blocks self-verification
+	Role                  UserRole            `json:"role" gorm:"default:user"`
+	SuspendedAt           *time.Time          `json:"suspended_at,omitempty"`
+	SuspendedUntil        *time.Time          `json:"suspended_until,omitempty"` // nil while suspended: until an admin lifts it
+	SuspensionReason      *string             `json:"suspension_reason,omitempty"`
+	BannedAt              *time.Time          `json:"banned_at,omitempty"`
+	BanReason             *string             `json:"ban_reason,omitempty"`
+	SalesAsBuyer          []Sale              `json:"-" gorm:"foreignKey:SellerID"`
+	SalesAsSeller         []Sale              `json:"-" gorm:"foreignKey:BuyerID"`
+	CreatedAt             time.Time           `json:"created_at"`
+	UpdatedAt             time.Time           `json:"updated_at"`
+}
+
+// Suspended reports whether the user is suspended at now
+func (u *User) Suspended(now time.Time) bool {
+	return u.SuspendedAt != nil && (u.SuspendedUntil == nil || u.SuspendedUntil.After(now))
+}
+
+// Banned reports whether the user is permanently banned
+func (u *User) Banned() bool {
+	return u.BannedAt != nil
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
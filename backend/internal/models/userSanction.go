@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SanctionType string
+
+const (
+	SanctionSuspend   SanctionType = "suspend"
+	SanctionUnsuspend SanctionType = "unsuspend"
+	SanctionBan       SanctionType = "ban"
+	SanctionUnban     SanctionType = "unban"
+)
+
+// UserSanction records every suspension and ban of a user, and when they were lifted
+type UserSanction struct {
+	ID        uuid.UUID    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    string       `json:"user_id" gorm:"not null;index"`
+	User      User         `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Type      SanctionType `json:"type" gorm:"not null"`
+	Reason    string       `json:"reason"`
+	EndsAt    *time.Time   `json:"ends_at,omitempty"` // end of a suspension, nil when indefinite
+	AdminID   *string      `json:"admin_id"`
+	Admin     *User        `json:"admin,omitempty" gorm:"foreignKey:AdminID;references:ID;constraint:OnDelete:SET NULL"`
+	ReportID  *uuid.UUID   `json:"report_id,omitempty" gorm:"type:uuid"` // report that led to the sanction
+	CreatedAt time.Time    `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// VerificationMethod is how a user got verified
+type VerificationMethod string
+
+const (
+	VerifiedByInstitutionalEmail VerificationMethod = "institutional_email" // Firebase verified the institutional email
+	VerifiedByEmailCode          VerificationMethod = "email_code"          // the user typed the code sent to the institutional email
+	VerifiedByAdmin              VerificationMethod = "admin"
+	VerifiedLegacy               VerificationMethod = "legacy" // set by the user before verification was server-side
+)
+
+// VerificationCode is the pending one-time code of a user, stored hashed
+type VerificationCode struct {
+	UserID    string    `json:"-" gorm:"primaryKey"`
+	User      User      `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	CodeHash  string    `json:"-" gorm:"not null"`
+	Email     string    `json:"email" gorm:"not null"`
+	Attempts  int       `json:"-" gorm:"not null;default:0"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
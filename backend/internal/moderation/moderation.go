@@ -0,0 +1,179 @@
+// Package moderation applies the enforcement actions of the admins: hiding a
+// listing, warning a user, suspending and banning an account. Every action
+// runs in the caller's transaction and notifies the affected user.
+package moderation
+
+import (
+	"api/internal/apperror"
+	"api/internal/models"
+	"api/internal/notify"
+	"api/internal/sales"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxSuspensionDays caps a suspension applied from a report
+const MaxSuspensionDays = 365
+
+// HideListing takes a listing down: its pending sale is cancelled, its open
+// offers are closed and only an admin can make it available again.
+func HideListing(tx *gorm.DB, listingID uuid.UUID, reason string) (models.Listing, error) {
+	listing, err := TakeDown(tx, listingID, models.Hidden)
+	if err != nil {
+		return listing, err
+	}
+
+	return listing, notify.Send(tx, listing.UserID, models.NotificationListingHidden, models.JSONMap{
+		"listing_id":    listing.ID,
+		"listing_title": listing.Title,
+		"reason":        reason,
+	})
+}
+
+// TakeDown cancels the pending sale, the open offers and the reservation of a
+// listing and sets its status to hidden or deleted. Its sales history stays.
+func TakeDown(tx *gorm.DB, listingID uuid.UUID, status models.Status) (models.Listing, error) {
+	var listing models.Listing
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&listing, "id = ?", listingID).Error; err != nil {
+		return listing, err
+	}
+
+	var pending []models.Sale
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("listing_id = ? AND status = ?", listing.ID, models.SalePending).
+		Find(&pending).Error; err != nil {
+		return listing, err
+	}
+	for i := range pending {
+		if err := sales.Close(tx, &pending[i], models.SaleCancelled); err != nil {
+			return listing, err
+		}
+	}
+	if err := sales.CloseOpenOffers(tx, listing.ID); err != nil {
+		return listing, err
+	}
+
+	listing.Status = status
+	listing.ReservedForID = nil
+	listing.ReservedUntil = nil
+	err := tx.Model(&listing).Select("status", "reserved_for_id", "reserved_until").Updates(&listing).Error
+	return listing, err
+}
+
+// Warn sends a moderation warning to userID
+func Warn(tx *gorm.DB, userID, message string) error {
+	return notify.Send(tx, userID, models.NotificationModerationWarning, models.JSONMap{
+		"message": message,
+	})
+}
+
+// restrictedUsers selects the banned users and the users suspended right now
+const restrictedUsers = `SELECT id FROM users WHERE banned_at IS NOT NULL
+	OR (suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > NOW()))`
+
+// VisibleSellers is a scope leaving out the listings of banned and suspended users
+func VisibleSellers(db *gorm.DB) *gorm.DB {
+	return db.Where("listings.user_id NOT IN (" + restrictedUsers + ")")
+}
+
+// AccountError returns the error a banned or suspended user gets on every
+// authenticated request, nil when the account is in good standing
+func AccountError(user models.User) error {
+	if user.Banned() {
+		return apperror.ErrAccountBanned
+	}
+	if user.Suspended(time.Now()) {
+		err := apperror.ErrAccountSuspended
+		if user.SuspendedUntil != nil {
+			err = err.With("until", user.SuspendedUntil.Format(time.RFC3339))
+		}
+		return err
+	}
+	return nil
+}
+
+// Suspend suspends sanction.UserID until sanction.EndsAt, or until lifted when
+// nil, replacing any suspension in place. The sanction is recorded.
+func Suspend(tx *gorm.DB, sanction models.UserSanction) error {
+	sanction.Type = models.SanctionSuspend
+	result := tx.Model(&models.User{}).Where("id = ?", sanction.UserID).Updates(map[string]any{
+		"suspended_at":      time.Now(),
+		"suspended_until":   sanction.EndsAt,
+		"suspension_reason": sanction.Reason,
+	})
+	if err := checkUpdated(result); err != nil {
+		return err
+	}
+	if err := tx.Create(&sanction).Error; err != nil {
+		return err
+	}
+
+	return notify.Send(tx, sanction.UserID, models.NotificationAccountSuspended, models.JSONMap{
+		"until":  sanction.EndsAt,
+		"reason": sanction.Reason,
+	})
+}
+
+// Unsuspend lifts the suspension of sanction.UserID and records it
+func Unsuspend(tx *gorm.DB, sanction models.UserSanction) error {
+	sanction.Type = models.SanctionUnsuspend
+	result := tx.Model(&models.User{}).
+		Where("id = ? AND suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > NOW())", sanction.UserID).
+		Updates(map[string]any{"suspended_at": nil, "suspended_until": nil, "suspension_reason": nil})
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return apperror.ErrUserNotSuspended
+	}
+	return tx.Create(&sanction).Error
+}
+
+// Ban permanently bans sanction.UserID and records it
+func Ban(tx *gorm.DB, sanction models.UserSanction) error {
+	sanction.Type = models.SanctionBan
+	result := tx.Model(&models.User{}).Where("id = ? AND banned_at IS NULL", sanction.UserID).Updates(map[string]any{
+		"banned_at":  time.Now(),
+		"ban_reason": sanction.Reason,
+	})
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return apperror.ErrUserAlreadyBanned
+	}
+	if err := tx.Create(&sanction).Error; err != nil {
+		return err
+	}
+
+	return notify.Send(tx, sanction.UserID, models.NotificationAccountBanned, models.JSONMap{
+		"reason": sanction.Reason,
+	})
+}
+
+// Unban lifts the ban of sanction.UserID and records it
+func Unban(tx *gorm.DB, sanction models.UserSanction) error {
+	sanction.Type = models.SanctionUnban
+	result := tx.Model(&models.User{}).Where("id = ? AND banned_at IS NOT NULL", sanction.UserID).
+		Updates(map[string]any{"banned_at": nil, "ban_reason": nil})
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return apperror.ErrUserNotBanned
+	}
+	return tx.Create(&sanction).Error
+}
+
+func checkUpdated(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperror.ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Package notify records the notifications of the notification center and
+// applies the preferences of each user.
+package notify
+
+import (
+	"api/internal/mail"
+	"api/internal/models"
+	"api/internal/realtime"
+
+	"gorm.io/gorm"
+)
+
+// Send notifies userID on every channel they did not opt out of for t: in-app,
+// recorded in tx and pushed to the user's event streams once tx commits, and
+// email, queued in the outbox in tx.
+func Send(tx *gorm.DB, userID string, t models.NotificationType, data models.JSONMap) error {
+	inApp, err := Enabled(tx, userID, t, models.ChannelInApp)
+	if err != nil {
+		return err
+	}
+	if inApp {
+		notification := models.Notification{UserID: userID, Type: t, Data: data}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+		if err := realtime.PublishRefTx(tx, userID, string(t), realtime.RefNotification, notification.ID); err != nil {
+			return err
+		}
+	}
+
+	email, err := Enabled(tx, userID, t, models.ChannelEmail)
+	if err != nil || !email || !mail.HasTemplate(string(t)) {
+		return err
+	}
+
+	var user models.User
+	if err := tx.Select("id, email, display_name").First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+
+	emailData := make(models.JSONMap, len(data)+1)
+	for k, v := range data {
+		emailData[k] = v
+	}
+	emailData["name"] = user.DisplayName
+
+	return mail.Enqueue(tx, user.Email, string(t), emailData)
+}
+
+// Enabled reports whether userID wants notifications of type t on channel, which is the default
+func Enabled(db *gorm.DB, userID string, t models.NotificationType, channel models.NotificationChannel) (bool, error) {
+	var prefs []models.NotificationPreference
+	if err := db.Where("user_id = ? AND type = ? AND channel = ?", userID, t, channel).Limit(1).Find(&prefs).Error; err != nil {
+		return false, err
+	}
+	return len(prefs) == 0 || prefs[0].Enabled, nil
+}
+
+// Preferences returns the preference of userID for every type and channel, defaults included
+func Preferences(db *gorm.DB, userID string) ([]models.NotificationPreference, error) {
+	var stored []models.NotificationPreference
+	if err := db.Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		t  models.NotificationType
+		ch models.NotificationChannel
+	}
+	byKey := make(map[key]models.NotificationPreference, len(stored))
+	for _, p := range stored {
+		byKey[key{p.Type, p.Channel}] = p
+	}
+
+	prefs := make([]models.NotificationPreference, 0, len(models.NotificationTypes)*len(models.NotificationChannels))
+	for _, t := range models.NotificationTypes {
+		for _, ch := range models.NotificationChannels {
+			p, ok := byKey[key{t, ch}]
+			if !ok {
+				p = models.NotificationPreference{UserID: userID, Type: t, Channel: ch, Enabled: true}
+			}
+			prefs = append(prefs, p)
+		}
+	}
+	return prefs, nil
+}
@@ -0,0 +1,244 @@
+// Package realtime delivers events to connected users. Events are published
+// with Postgres NOTIFY, and every API replica LISTENs and forwards them to the
+// streams of its own subscribers, so delivery doesn't depend on which replica
+// a client is connected to.
+package realtime
+
+import (
+	"api/internal/models"
+	"api/internal/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// channel is the Postgres NOTIFY channel of the events
+const channel = "brecho_events"
+
+// maxPayload stays under the 8000 bytes limit of a NOTIFY payload. Rows that
+// hold user text (messages, notifications) are published by reference instead.
+const maxPayload = 7900
+
+// subscriberBuffer is how many events a slow stream can lag behind before events are dropped
+const subscriberBuffer = 32
+
+// Event types. Notifications are published with their own type
+// (models.NotificationType) by the notify package.
+const (
+	EventMessageNew       = "message.new"
+	EventListingFavorited = "listing.favorited"
+)
+
+// Event is sent to the streams of one user
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Kinds of rows an event can reference, loaded by the listener as its data
+const (
+	RefMessage      = "message"
+	RefNotification = "notification"
+)
+
+// Ref points at the row holding the data of an event
+type Ref struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+}
+
+// envelope is the NOTIFY payload. Events published by reference carry Ref
+// instead of their data.
+type envelope struct {
+	UserID string `json:"user_id"`
+	Event  Event  `json:"event"`
+	Ref    *Ref   `json:"ref,omitempty"`
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers = map[string]map[chan Event]struct{}{}
+)
+
+// Publish sends an event to every stream of userID, on any replica. Failures
+// are only logged: real-time delivery never fails the request that caused it.
+func Publish(userID, eventType string, data any) {
+	if err := PublishTx(repository.DB, userID, eventType, data); err != nil {
+		log.Printf("⚠️ realtime: failed to publish %s event: %v", eventType, err)
+	}
+}
+
+// PublishTx publishes the event through tx: Postgres only delivers a NOTIFY
+// when the transaction commits, so a rolled back change never reaches the streams.
+func PublishTx(tx *gorm.DB, userID, eventType string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return notify(tx, envelope{UserID: userID, Event: newEvent(eventType, raw)})
+}
+
+// PublishRef is Publish for an event whose data is the row kind/id, which
+// every replica loads before delivering it
+func PublishRef(userID, eventType, kind string, id any) {
+	if err := PublishRefTx(repository.DB, userID, eventType, kind, id); err != nil {
+		log.Printf("⚠️ realtime: failed to publish %s event: %v", eventType, err)
+	}
+}
+
+// PublishRefTx is PublishTx for an event published by reference
+func PublishRefTx(tx *gorm.DB, userID, eventType, kind string, id any) error {
+	return notify(tx, envelope{
+		UserID: userID,
+		Event:  newEvent(eventType, nil),
+		Ref:    &Ref{Kind: kind, ID: fmt.Sprint(id)},
+	})
+}
+
+func newEvent(eventType string, data json.RawMessage) Event {
+	return Event{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+}
+
+func notify(tx *gorm.DB, env envelope) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxPayload {
+		return fmt.Errorf("event too large (%d bytes)", len(payload))
+	}
+
+	return tx.Exec("SELECT pg_notify(?, ?)", channel, string(payload)).Error
+}
+
+// load returns the data of an event published by reference
+func load(ctx context.Context, ref Ref) (json.RawMessage, error) {
+	var row any
+	switch ref.Kind {
+	case RefMessage:
+		row = &models.Message{}
+	case RefNotification:
+		row = &models.Notification{}
+	default:
+		return nil, fmt.Errorf("unknown reference kind %q", ref.Kind)
+	}
+	if err := repository.DB.WithContext(ctx).First(row, "id = ?", ref.ID).Error; err != nil {
+		return nil, err
+	}
+	return json.Marshal(row)
+}
+
+// Subscribe returns the events of userID and a function to stop receiving them
+func Subscribe(userID string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	mu.Lock()
+	if subscribers[userID] == nil {
+		subscribers[userID] = map[chan Event]struct{}{}
+	}
+	subscribers[userID][ch] = struct{}{}
+	mu.Unlock()
+
+	return ch, func() {
+		mu.Lock()
+		delete(subscribers[userID], ch)
+		if len(subscribers[userID]) == 0 {
+			delete(subscribers, userID)
+		}
+		mu.Unlock()
+	}
+}
+
+// subscribed reports whether userID has a stream on this replica
+func subscribed(userID string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(subscribers[userID]) > 0
+}
+
+// deliver forwards an event to the local streams of userID
+func deliver(userID string, ev Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for ch := range subscribers[userID] {
+		select {
+		case ch <- ev:
+		default:
+			// the stream is not keeping up, it will reconnect and refetch
+		}
+	}
+}
+
+// Listen forwards the NOTIFY events to the local subscribers until ctx is
+// done, reconnecting with backoff when the connection drops
+func Listen(ctx context.Context, dsn string) {
+	backoff := time.Second
+	for {
+		err := listen(ctx, dsn, func() { backoff = time.Second })
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("⚠️ realtime: listener stopped: %v, reconnecting in %s", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+func listen(ctx context.Context, dsn string, connected func()) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	log.Println("✅ Realtime listener connected")
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var env envelope
+		if err := json.Unmarshal([]byte(notification.Payload), &env); err != nil {
+			log.Printf("⚠️ realtime: invalid payload: %v", err)
+			continue
+		}
+		if !subscribed(env.UserID) {
+			continue
+		}
+		if env.Ref != nil {
+			data, err := load(ctx, *env.Ref)
+			if err != nil {
+				log.Printf("⚠️ realtime: failed to load %s %s: %v", env.Ref.Kind, env.Ref.ID, err)
+				continue
+			}
+			env.Event.Data = data
+		}
+		deliver(env.UserID, env.Event)
+	}
+}
@@ -0,0 +1,205 @@
+// Package reconcile keeps the image storage and the listing_images table in sync.
+package reconcile
+
+import (
+	"api/internal/config"
+	"api/internal/models"
+	"api/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultGracePeriod protects fresh uploads: a presigned object is stored
+// before the client registers it, so young objects are never deleted.
+const DefaultGracePeriod = 24 * time.Hour
+
+// advisoryLockID identifies the reconciler lock, so only one run (across all
+// API replicas) happens at a time
+const advisoryLockID = 727001
+
+var ErrAlreadyRunning = errors.New("reconciler already running")
+
+// Options of a reconciler run
+type Options struct {
+	DryRun      bool
+	GracePeriod time.Duration
+	Trigger     string  // "cron" or "manual"
+	TriggeredBy *string // admin who triggered a manual run
+}
+
+// GracePeriodFromEnv reads RECONCILE_GRACE_PERIOD (e.g. "36h"), falling back to DefaultGracePeriod
+func GracePeriodFromEnv() time.Duration {
+	if v := os.Getenv("RECONCILE_GRACE_PERIOD"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("⚠️ invalid RECONCILE_GRACE_PERIOD %q, using %s", v, DefaultGracePeriod)
+	}
+	return DefaultGracePeriod
+}
+
+// Scheduled is the cron entry point. It only deletes when RECONCILE_DELETE is "true".
+func Scheduled() {
+	opts := Options{
+		DryRun:      os.Getenv("RECONCILE_DELETE") != "true",
+		GracePeriod: GracePeriodFromEnv(),
+		Trigger:     "cron",
+	}
+
+	run, err := Run(context.Background(), opts)
+	if err != nil {
+		log.Printf("❌ reconciler failed: %v", err)
+		return
+	}
+	log.Printf("✅ reconciler run %s: %d orphan objects (%d deleted), %d missing images (%d deleted), %d errors, dry run: %t",
+		run.ID, len(run.OrphanObjects), run.DeletedObjects, len(run.MissingImages), run.DeletedImages, len(run.Errors), run.DryRun)
+}
+
+// Run compares the storage with the database, deletes orphan objects older
+// than the grace period and images whose object is gone (unless DryRun), and
+// persists the report.
+func Run(ctx context.Context, opts Options) (*models.ReconcileRun, error) {
+	if opts.GracePeriod <= 0 {
+		opts.GracePeriod = DefaultGracePeriod
+	}
+
+	var run *models.ReconcileRun
+
+	// The transaction only holds the advisory lock for the duration of the run
+	err := repository.DB.Transaction(func(lockTx *gorm.DB) error {
+		var locked bool
+		if err := lockTx.Raw("SELECT pg_try_advisory_xact_lock(?)", advisoryLockID).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return ErrAlreadyRunning
+		}
+
+		run = &models.ReconcileRun{
+			Trigger:       opts.Trigger,
+			TriggeredByID: opts.TriggeredBy,
+			DryRun:        opts.DryRun,
+			GracePeriod:   opts.GracePeriod.String(),
+			Status:        models.ReconcileRunning,
+			StartedAt:     time.Now(),
+			OrphanObjects: models.StringList{},
+			MissingImages: models.StringList{},
+			Errors:        models.StringList{},
+		}
+		if err := repository.DB.Create(run).Error; err != nil {
+			return err
+		}
+
+		if err := reconcile(ctx, run, opts); err != nil {
+			run.Status = models.ReconcileFailed
+			run.Errors = append(run.Errors, err.Error())
+		} else {
+			run.Status = models.ReconcileCompleted
+		}
+		now := time.Now()
+		run.FinishedAt = &now
+
+		return repository.DB.Save(run).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+func reconcile(ctx context.Context, run *models.ReconcileRun, opts Options) error {
+	// 1. Read every referenced key before listing the storage: an image row
+	// always comes after its object, so rows read now have their object listed below
+	var images []models.ListingImage
+	if err := repository.DB.Find(&images).Error; err != nil {
+		return fmt.Errorf("failed to load images: %w", err)
+	}
+	run.ImagesScanned = len(images)
+
+	referenced := make(map[string]bool, len(images))
+	for _, img := range images {
+		referenced[img.Key] = true
+	}
+
+	// Variant objects (thumbnail, card) are referenced through their own table
+	var variantKeys []string
+	if err := repository.DB.Model(&models.ListingImageVariant{}).Pluck("key", &variantKeys).Error; err != nil {
+		return fmt.Errorf("failed to load image variants: %w", err)
+	}
+	for _, key := range variantKeys {
+		referenced[key] = true
+	}
+
+	// Presigned keys not attached yet are still in use
+	var pendingKeys []string
+	if err := repository.DB.Model(&models.PendingUpload{}).Where("expires_at > ?", time.Now()).Pluck("key", &pendingKeys).Error; err != nil {
+		return fmt.Errorf("failed to load pending uploads: %w", err)
+	}
+	for _, key := range pendingKeys {
+		referenced[key] = true
+	}
+
+	// 2. List all objects in the storage
+	objects, err := config.Storage.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list storage: %w", err)
+	}
+	run.ObjectsScanned = len(objects)
+
+	stored := make(map[string]bool, len(objects))
+	cutoff := time.Now().Add(-opts.GracePeriod)
+	for _, obj := range objects {
+		stored[obj.Key] = true
+		if referenced[obj.Key] {
+			continue
+		}
+		if obj.LastModified.After(cutoff) {
+			run.SkippedRecent++
+			continue
+		}
+		run.OrphanObjects = append(run.OrphanObjects, obj.Key)
+	}
+
+	// 3. Images whose object is gone
+	var missing []models.ListingImage
+	for _, img := range images {
+		if !stored[img.Key] {
+			missing = append(missing, img)
+			run.MissingImages = append(run.MissingImages, img.Key)
+		}
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+
+	// 4. Delete orphan objects in batches
+	failed := config.Storage.DeleteMany(ctx, run.OrphanObjects)
+	run.DeletedObjects = len(run.OrphanObjects) - len(failed)
+	for key, err := range failed {
+		run.Errors = append(run.Errors, fmt.Sprintf("failed to delete object %s: %v", key, err))
+	}
+
+	// 5. Delete broken images
+	for _, img := range missing {
+		if err := repository.DB.Delete(&img).Error; err != nil {
+			run.Errors = append(run.Errors, fmt.Sprintf("failed to delete image %s: %v", img.ID, err))
+			continue
+		}
+		run.DeletedImages++
+	}
+
+	// Expired pending uploads can go as well
+	if err := repository.DB.Where("expires_at <= ?", time.Now()).Delete(&models.PendingUpload{}).Error; err != nil {
+		run.Errors = append(run.Errors, fmt.Sprintf("failed to delete expired pending uploads: %v", err))
+	}
+
+	return nil
+}
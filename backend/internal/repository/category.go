@@ -0,0 +1,20 @@
+package repository
+
+// CategorySubtreeIDs returns the id of the category and of all its descendants.
+// UNION (instead of UNION ALL) stops the recursion if the tree ever has a cycle.
+func CategorySubtreeIDs(categoryID int) ([]int, error) {
+	var ids []int
+	err := DB.Raw(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT id FROM subtree
+	`, categoryID).Scan(&ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
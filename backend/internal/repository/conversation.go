@@ -0,0 +1,21 @@
+package repository
+
+// UnreadMessageCount counts the messages sent to userID that they have not read yet
+func UnreadMessageCount(userID string) (int64, error) {
+	var count int64
+	err := DB.Table("messages").
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Where("conversations.buyer_id = ? OR conversations.seller_id = ?", userID, userID).
+		Where("messages.sender_id <> ? AND messages.read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// IsBlocked reports whether either user has blocked the other
+func IsBlocked(userID, otherID string) (bool, error) {
+	var count int64
+	err := DB.Table("user_blocks").
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userID, otherID, otherID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
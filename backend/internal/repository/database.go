@@ -6,18 +6,18 @@ import (
 	"os"
 	"time"
 
-	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"api/internal/models"
 )
 
 var DB *gorm.DB
 
-func Connect() {
-	// define the connection
-	dns := fmt.Sprintf(
+// DSN returns the connection string of the database, built from the DB_* env vars
+func DSN() string {
+	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
 		os.Getenv("DB_HOST"),
 		os.Getenv("DB_USER"),
@@ -25,9 +25,17 @@ func Connect() {
 		os.Getenv("DB_NAME"),
 		os.Getenv("DB_PORT"),
 	)
+}
+
+func Connect() {
+	// define the connection
+	dns := DSN()
 
 	// connect to the database
-	db, err := gorm.Open(postgres.Open(dns), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dns), &gorm.Config{
+		// Lets handlers match gorm.ErrDuplicatedKey and gorm.ErrForeignKeyViolated
+		TranslateError: true,
+	})
 	if err != nil {
 		fmt.Println("Error connecting to the database: ", err)
 		return
@@ -49,32 +57,102 @@ func Connect() {
 	log.Println("✅ Database connected successfully")
 }
 
-func SearchListingsFTS(query string, page, pageSize int) ([]models.Listing, error) {
-	var ids []uuid.UUID
-	sql := `
-		SELECT id
-		FROM listings
-		WHERE status = 'available'
-			AND title_search @@ websearch_to_tsquery(?)
-		ORDER BY ts_rank(title_search, websearch_to_tsquery(?)) DESC
-		LIMIT ? OFFSET ?
-		`
-
-	err := DB.Raw(sql, query, query, pageSize, (page-1)*pageSize).Scan(&ids).Error
-	if err != nil {
-		log.Fatal("❌ Failed to scan listings using fts: ", err)
-		return nil, err
+// trigramThreshold is the minimum word similarity for a listing to match a
+// search term through pg_trgm. The trigram match is OR'd with the full-text
+// one, so titles and keywords with typos (like "iphoe" or "samsumg") are
+// found too.
+const trigramThreshold = 0.3
+
+// ListingSearch describes a full-text search over listings.
+// Filters is applied to both the count and the page query, so callers can
+// reuse the same WHERE clauses they use on the regular feeds.
+// Order defaults to OrderByRelevance when nil. SkipCount avoids the COUNT
+// query for keyset paginated feeds, which do not report a total.
+type ListingSearch struct {
+	Query     string
+	Filters   func(*gorm.DB) *gorm.DB
+	Order     func(*gorm.DB) *gorm.DB
+	Limit     int
+	Offset    int
+	SkipCount bool
+}
+
+// SearchSession runs fn inside a transaction configured for MatchListings.
+// Every query that uses MatchListings must go through tx.
+func SearchSession(fn func(tx *gorm.DB) error) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// The <% operator uses this threshold and can be served by the trigram indexes
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL pg_trgm.word_similarity_threshold = %g", trigramThreshold)).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// MatchListings restricts a listings query to rows matching q either through
+// the title_search tsvector (title, keywords and description) or through
+// trigram similarity on title and keywords.
+func MatchListings(q string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			`(listings.title_search @@ websearch_to_tsquery('simple', ?) OR ? <% listings.title OR ? <% listings.keywords)`,
+			q, q, q,
+		)
+	}
+}
+
+// OrderByRelevance orders a listings query by how well it matches q.
+func OrderByRelevance(q string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL: `ts_rank(listings.title_search, websearch_to_tsquery('simple', ?)) +
+				greatest(word_similarity(?, listings.title), word_similarity(?, listings.keywords)) DESC,
+				listings.created_at DESC, listings.id DESC`,
+			Vars:               []interface{}{q, q, q},
+			WithoutParentheses: true,
+		}})
 	}
+}
 
+// SearchListingsFTS runs a ranked full-text search over listings, also
+// matching by trigram similarity on title and keywords. It returns the
+// requested page ordered by relevance and the total number of matches.
+func SearchListingsFTS(search ListingSearch) ([]models.Listing, int64, error) {
 	var listings []models.Listing
-	if len(ids) > 0 {
-		safeFields := "id, display_name, slug, photo_url, university, verified, role, created_at"
-		DB.Preload("User", func(db *gorm.DB) *gorm.DB {
+	var total int64
+
+	filters := search.Filters
+	if filters == nil {
+		filters = func(db *gorm.DB) *gorm.DB { return db }
+	}
+	order := search.Order
+	if order == nil {
+		order = OrderByRelevance(search.Query)
+	}
+
+	err := SearchSession(func(tx *gorm.DB) error {
+		if !search.SkipCount {
+			if err := tx.Model(&models.Listing{}).
+				Scopes(filters, MatchListings(search.Query)).
+				Count(&total).Error; err != nil {
+				return err
+			}
+		}
+
+		safeFields := "id, display_name, slug, photo_url, university, institution_id, verified, role, created_at"
+		return tx.Preload("User", func(db *gorm.DB) *gorm.DB {
 			return db.Select(safeFields)
 		}).
 			Preload("Category").
-			Find(&listings, "id IN ?", ids)
+			Scopes(filters, MatchListings(search.Query), order).
+			Limit(search.Limit).
+			Offset(search.Offset).
+			Find(&listings).Error
+	})
+	if err != nil {
+		log.Println("❌ Failed to search listings using fts: ", err)
+		return nil, 0, err
 	}
 
-	return listings, nil
+	return listings, total, nil
 }
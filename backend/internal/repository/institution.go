@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"api/internal/models"
+	"strings"
+)
+
+// ResolveInstitution returns the active institution of an email, nil when
+// the domain belongs to none
+func ResolveInstitution(email string) (*models.Institution, error) {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil, nil
+	}
+	domain := email[at+1:]
+
+	var institutions []models.Institution
+	if err := DB.Preload("Domains").Where("active").Find(&institutions).Error; err != nil {
+		return nil, err
+	}
+
+	var best *models.Institution
+	bestScore := 0
+	for i := range institutions {
+		if score, ok := institutions[i].MatchDomain(domain); ok && score > bestScore {
+			best, bestScore = &institutions[i], score
+		}
+	}
+	return best, nil
+}
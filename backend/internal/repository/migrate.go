@@ -9,29 +9,54 @@ func Migrate() {
 	var err error
 
 	ennableUUIDExtension()
+	enableTrigramExtension()
 	createConditionEnum()
 	createReportEnums()
 	createStatusEnum()
 
 	err = DB.AutoMigrate(
+		&models.Institution{},
+		&models.InstitutionDomain{},
 		&models.User{},
 		&models.Category{},
 		&models.Listing{},
 		&models.ListingImage{},
+		&models.ListingImageVariant{},
+		&models.PendingUpload{},
+		&models.ReconcileRun{},
 		&models.Favorite{},
 		&models.Report{},
+		&models.ReportNote{},
+		&models.ReportStatusChange{},
+		&models.UserSanction{},
+		&models.AdminAction{},
+		&models.VerificationCode{},
 		&models.Sale{},
+		&models.Offer{},
 		&models.Review{},
+		&models.Conversation{},
+		&models.Message{},
+		&models.UserBlock{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.OutboxEmail{},
 	)
-
-	createListingsIndexes()
-
 	if err != nil {
 		log.Fatal("Failed to migrate User model: ", err)
 	}
 
+	createListingsIndexes()
+	createSalesIndexes()
+	migrateReviewParties()
+	createAdminActionsGuard()
+	ensureSuperadmin()
+	migrateVerifications()
+	seedInstitutions()
+	createUniversitySync()
+
 	enableTSVectorSearchColumn() // shoud be called after all table alters (probably)
 	crateTSIndex()               // deixando tudo mai rapidop
+	createTrigramIndexes()
 
 	log.Println("✅ Database migrated successfully")
 }
@@ -44,6 +69,14 @@ func ennableUUIDExtension() {
 	}
 }
 
+// Enable the pg_trgm extension used for typo-tolerant search
+func enableTrigramExtension() {
+	err := DB.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`).Error
+	if err != nil {
+		log.Fatal("Failed to enable pg_trgm extension: ", err)
+	}
+}
+
 // Create the condition_enum type if it doesn't exist
 func createConditionEnum() {
 	err := DB.Exec(`
@@ -77,6 +110,11 @@ func createReportEnums() {
 	`).Error; err != nil {
 		log.Fatal("❌ Failed to create report enum types:", err)
 	}
+
+	// Values added later (ADD VALUE can't run in the DO block above)
+	if err := DB.Exec(`ALTER TYPE report_target_type_enum ADD VALUE IF NOT EXISTS 'review'`).Error; err != nil {
+		log.Fatal("❌ Failed to add review to report_target_type_enum:", err)
+	}
 }
 
 func createStatusEnum() {
@@ -91,6 +129,14 @@ func createStatusEnum() {
 	if err != nil {
 		log.Fatal("❌ Failed to create enum status_enum:", err)
 	}
+
+	// Values added later (ADD VALUE can't run in the DO block above)
+	if err := DB.Exec(`ALTER TYPE status_enum ADD VALUE IF NOT EXISTS 'reserved' AFTER 'available'`).Error; err != nil {
+		log.Fatal("❌ Failed to add reserved to status_enum:", err)
+	}
+	if err := DB.Exec(`ALTER TYPE status_enum ADD VALUE IF NOT EXISTS 'hidden'`).Error; err != nil {
+		log.Fatal("❌ Failed to add hidden to status_enum:", err)
+	}
 }
 
 func createListingsIndexes() {
@@ -98,13 +144,179 @@ func createListingsIndexes() {
 	DB.Exec(`CREATE INDEX IF NOT EXISTS idx_listings_search ON listings (category_id, price, created_at DESC)`)
 }
 
+// A listing used to have a single sale. Now rejected, cancelled and expired
+// proposals stay as history, and only one pending or confirmed sale is allowed.
+func createSalesIndexes() {
+	DB.Exec(`ALTER TABLE sales DROP CONSTRAINT IF EXISTS uni_sales_listing_id`)
+	DB.Exec(`ALTER TABLE sales DROP CONSTRAINT IF EXISTS sales_listing_id_key`)
+	err := DB.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_sales_listing_active
+		ON sales (listing_id) WHERE status IN ('pending', 'confirmed')
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to create sales indexes:", err)
+	}
+
+	err = DB.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_offers_listing_buyer_open
+		ON offers (listing_id, buyer_id) WHERE status = 'open'
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to create offers indexes:", err)
+	}
+}
+
+// Reviews used to be written only by buyers, one per sale: fill in the
+// reviewer and reviewed users of those, then drop the old one-per-sale rule.
+func migrateReviewParties() {
+	err := DB.Exec(`
+		UPDATE reviews SET reviewer_id = sales.buyer_id, reviewed_id = sales.seller_id
+		FROM sales
+		WHERE sales.id = reviews.sale_id AND reviews.reviewer_id IS NULL
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate reviews:", err)
+	}
+
+	DB.Exec(`ALTER TABLE reviews DROP CONSTRAINT IF EXISTS uni_reviews_sale_id`)
+	DB.Exec(`ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_sale_id_key`)
+}
+
+// The audit log is append-only: updates and deletes of admin_actions fail
+func createAdminActionsGuard() {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION admin_actions_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'admin_actions is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS admin_actions_append_only ON admin_actions`,
+		`CREATE TRIGGER admin_actions_append_only
+			BEFORE UPDATE OR DELETE OR TRUNCATE ON admin_actions
+			FOR EACH STATEMENT EXECUTE FUNCTION admin_actions_append_only()`,
+	}
+	for _, stmt := range statements {
+		if err := DB.Exec(stmt).Error; err != nil {
+			log.Fatal("❌ Failed to create the admin_actions guard:", err)
+		}
+	}
+}
+
+// Roles used to be only user and admin: without a superadmin, the oldest admin
+// becomes one so someone can still grant roles
+func ensureSuperadmin() {
+	err := DB.Exec(`
+		UPDATE users SET role = ?
+		WHERE id = (SELECT id FROM users WHERE role = ? ORDER BY created_at LIMIT 1)
+		AND NOT EXISTS (SELECT 1 FROM users WHERE role = ?)
+	`, models.RoleSuperadmin, models.RoleAdmin, models.RoleSuperadmin).Error
+	if err != nil {
+		log.Fatal("❌ Failed to ensure a superadmin:", err)
+	}
+}
+
+// Users used to verify themselves: keep them verified, marked as legacy
+func migrateVerifications() {
+	err := DB.Exec(`
+		UPDATE users SET verification_method = ?, verified_at = updated_at
+		WHERE verified AND verification_method IS NULL
+	`, models.VerifiedLegacy).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate verifications:", err)
+	}
+}
+
+// The allowed institutions used to be hardcoded: create them on the first run
+// and link their users by the university name
+func seedInstitutions() {
+	var count int64
+	if err := DB.Model(&models.Institution{}).Count(&count).Error; err != nil {
+		log.Fatal("❌ Failed to count institutions:", err)
+	}
+	if count == 0 {
+		city := "São Carlos"
+		institutions := []models.Institution{
+			{Name: "Universidade de São Paulo", ShortName: "USP", Domains: []models.InstitutionDomain{{Pattern: "usp.br"}}, City: &city},
+			{Name: "Universidade Federal de São Carlos", ShortName: "UFSCar", Domains: []models.InstitutionDomain{{Pattern: "estudante.ufscar.br"}}, City: &city},
+			{Name: "Instituto Federal de São Paulo", ShortName: "IFSP", Domains: []models.InstitutionDomain{{Pattern: "aluno.ifsp.edu.br"}}, City: &city},
+		}
+		if err := DB.Create(&institutions).Error; err != nil {
+			log.Fatal("❌ Failed to seed institutions:", err)
+		}
+	}
+
+	err := DB.Exec(`
+		UPDATE users SET institution_id = institutions.id
+		FROM institutions
+		WHERE users.institution_id IS NULL AND users.university = institutions.name
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to link users to institutions:", err)
+	}
+}
+
+// users.university is a copy of the name of the user's institution, so the
+// feeds and profiles don't need a join. The database keeps it in sync with
+// institution_id and with renames of the institution. Users no institution
+// matched yet keep the university they typed until theirs is registered.
+func createUniversitySync() {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION users_sync_university() RETURNS trigger AS $$
+		BEGIN
+			NEW.university := (SELECT name FROM institutions WHERE id = NEW.institution_id);
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS users_sync_university ON users`,
+		`CREATE TRIGGER users_sync_university
+		BEFORE INSERT OR UPDATE OF institution_id, university ON users
+		FOR EACH ROW EXECUTE FUNCTION users_sync_university()`,
+		`CREATE OR REPLACE FUNCTION institutions_sync_university() RETURNS trigger AS $$
+		BEGIN
+			UPDATE users SET university = NEW.name WHERE institution_id = NEW.id;
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS institutions_sync_university ON institutions`,
+		`CREATE TRIGGER institutions_sync_university
+		AFTER UPDATE OF name ON institutions
+		FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+		EXECUTE FUNCTION institutions_sync_university()`,
+	}
+	for _, stmt := range statements {
+		if err := DB.Exec(stmt).Error; err != nil {
+			log.Fatal("❌ Failed to create the university sync:", err)
+		}
+	}
+}
+
 func enableTSVectorSearchColumn() {
+	// The column used to index only the title and keywords: recreate it with
+	// the description (its index is dropped along and rebuilt by crateTSIndex)
 	err := DB.Exec(`
+		DO $$
+		BEGIN
+			IF EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_name = 'listings' AND column_name = 'title_search'
+				AND generation_expression NOT LIKE '%description%'
+			) THEN
+				ALTER TABLE listings DROP COLUMN title_search;
+			END IF;
+		END
+		$$;
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to drop outdated tsvector column: ", err)
+	}
+
+	err = DB.Exec(`
 		ALTER TABLE listings
 		ADD COLUMN IF NOT EXISTS title_search tsvector
 		GENERATED ALWAYS AS (
 			setweight(to_tsvector('simple', coalesce(title, '')), 'A') || ' ' ||
-			setweight(to_tsvector('simple', coalesce(keywords, '')), 'B') :: tsvector
+			setweight(to_tsvector('simple', coalesce(keywords, '')), 'B') || ' ' ||
+			setweight(to_tsvector('simple', coalesce(description, '')), 'C') :: tsvector
 		) stored;
  	`).Error
 
@@ -122,3 +334,12 @@ func crateTSIndex() {
 		log.Fatal("❌ Failed to generate index for ts vector: ", err)
 	}
 }
+
+func createTrigramIndexes() {
+	if err := DB.Exec(`CREATE INDEX IF NOT EXISTS idx_listings_title_trgm ON listings USING GIN(title gin_trgm_ops)`).Error; err != nil {
+		log.Fatal("❌ Failed to generate trigram index for title: ", err)
+	}
+	if err := DB.Exec(`CREATE INDEX IF NOT EXISTS idx_listings_keywords_trgm ON listings USING GIN(keywords gin_trgm_ops)`).Error; err != nil {
+		log.Fatal("❌ Failed to generate trigram index for keywords: ", err)
+	}
+}
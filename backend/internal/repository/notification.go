@@ -0,0 +1,10 @@
+package repository
+
+// UnreadNotificationCount counts the notifications of userID not read yet
+func UnreadNotificationCount(userID string) (int64, error) {
+	var count int64
+	err := DB.Table("notifications").
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"api/internal/models"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// reputationTrendWindow is the period compared by the reputation trend
+const reputationTrendWindow = 90 * 24 * time.Hour
+
+// Reputation aggregates the visible reviews userID received, reviewed by the
+// other side of the sale (role is the role of the reviewer)
+func Reputation(userID string, role models.ReviewRole) (models.Reputation, error) {
+	rep := models.Reputation{Distribution: map[int]int64{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+
+	received := DB.Model(&models.Review{}).Where("reviewed_id = ? AND role = ? AND hidden_at IS NULL", userID, role)
+
+	var dist []struct {
+		Rating int
+		Count  int64
+	}
+	if err := received.Session(&gorm.Session{}).Select("rating, COUNT(*) AS count").Group("rating").Scan(&dist).Error; err != nil {
+		return rep, err
+	}
+	var sum int64
+	for _, d := range dist {
+		rep.Distribution[d.Rating] = d.Count
+		rep.Count += d.Count
+		sum += int64(d.Rating) * d.Count
+	}
+	if rep.Count > 0 {
+		rep.Average = round2(float64(sum) / float64(rep.Count))
+	}
+
+	now := time.Now()
+	recent, err := averageBetween(received, now.Add(-reputationTrendWindow), now)
+	if err != nil {
+		return rep, err
+	}
+	previous, err := averageBetween(received, now.Add(-2*reputationTrendWindow), now.Add(-reputationTrendWindow))
+	if err != nil {
+		return rep, err
+	}
+	rep.Trend = models.ReputationTrend{RecentAverage: recent, PreviousAverage: previous, Direction: "unknown"}
+	if recent != nil && previous != nil {
+		switch diff := *recent - *previous; {
+		case diff >= 0.25:
+			rep.Trend.Direction = "up"
+		case diff <= -0.25:
+			rep.Trend.Direction = "down"
+		default:
+			rep.Trend.Direction = "stable"
+		}
+	}
+
+	return rep, nil
+}
+
+// averageBetween returns the average rating of the reviews created in [from, to), nil without reviews
+func averageBetween(query *gorm.DB, from, to time.Time) (*float64, error) {
+	var avg *float64
+	err := query.Session(&gorm.Session{}).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Select("AVG(rating)").
+		Scan(&avg).Error
+	if err != nil || avg == nil {
+		return nil, err
+	}
+	rounded := round2(*avg)
+	return &rounded, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
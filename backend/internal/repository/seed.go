@@ -64,34 +64,42 @@ func Seed() error {
 		/* ------------------------------------------------------------------
 		   2. Usuários
 		------------------------------------------------------------------*/
+		var usp, ufscar models.Institution
+		if err := tx.First(&usp, "short_name = ?", "USP").Error; err != nil {
+			return err
+		}
+		if err := tx.First(&ufscar, "short_name = ?", "UFSCar").Error; err != nil {
+			return err
+		}
+
 		users := []*models.User{
 			{
-				ID:          "kCIjyDgvJpNbpCiaePDXHlQwkU02", // Fixando uid (gerado pelo firebase)
-				DisplayName: "Admin",
-				Email:       "admin@example.com",
-				PhotoURL:    StringPtr("https://i.pravatar.cc/150?u=kCIjyDgvJpNbpCiaePDXHlQwkU02"),
-				Role:        models.RoleAdmin,
-				University:  StringPtr("Universidade Federal de São Carlos"),
-				Whatsapp:    StringPtr("5511999999999"),
-				Verified:    true,
+				ID:            "kCIjyDgvJpNbpCiaePDXHlQwkU02", // Fixando uid (gerado pelo firebase)
+				DisplayName:   "Admin",
+				Email:         "admin@example.com",
+				PhotoURL:      StringPtr("https://i.pravatar.cc/150?u=kCIjyDgvJpNbpCiaePDXHlQwkU02"),
+				Role:          models.RoleSuperadmin,
+				InstitutionID: &ufscar.ID,
+				Whatsapp:      StringPtr("5511999999999"),
+				Verified:      true,
 			},
 			{
-				ID:          "1UlfK3Ha5jdmreJQzG0L5EMR2BI3",
-				DisplayName: "João Silva",
-				Email:       "joao@example.com",
-				PhotoURL:    StringPtr("https://i.pravatar.cc/150?u=1UlfK3Ha5jdmreJQzG0L5EMR2BI3"),
-				University:  StringPtr("Universidade de São Paulo"),
-				Whatsapp:    StringPtr("5511999999999"),
-				Verified:    true,
+				ID:            "1UlfK3Ha5jdmreJQzG0L5EMR2BI3",
+				DisplayName:   "João Silva",
+				Email:         "joao@example.com",
+				PhotoURL:      StringPtr("https://i.pravatar.cc/150?u=1UlfK3Ha5jdmreJQzG0L5EMR2BI3"),
+				InstitutionID: &usp.ID,
+				Whatsapp:      StringPtr("5511999999999"),
+				Verified:      true,
 			},
 			{
-				ID:          "pSKSJ1PWTTYqSn1GiB2zgQJ2NUj2",
-				DisplayName: "Maria Souza",
-				Email:       "maria@example.com",
-				PhotoURL:    StringPtr("https://i.pravatar.cc/150?u=pSKSJ1PWTTYqSn1GiB2zgQJ2NUj2"),
-				University:  StringPtr("Universidade de São Paulo"),
-				Whatsapp:    StringPtr("5511999999999"),
-				Verified:    false,
+				ID:            "pSKSJ1PWTTYqSn1GiB2zgQJ2NUj2",
+				DisplayName:   "Maria Souza",
+				Email:         "maria@example.com",
+				PhotoURL:      StringPtr("https://i.pravatar.cc/150?u=pSKSJ1PWTTYqSn1GiB2zgQJ2NUj2"),
+				InstitutionID: &usp.ID,
+				Whatsapp:      StringPtr("5511999999999"),
+				Verified:      false,
 			},
 		}
 		for _, u := range users {
@@ -1,8 +1,10 @@
 package router
 
 import (
+	"api/internal/config"
 	"api/internal/handler"
 	"api/internal/middleware"
+	"api/internal/models"
 	"os"
 
 	"github.com/gin-contrib/cors"
@@ -22,6 +24,9 @@ func New() *gin.Engine {
 		AllowCredentials: true,
 	}))
 
+	// Renderiza os erros dos handlers com código e mensagem traduzida
+	router.Use(middleware.Errors)
+
 	api := router.Group("/brechoapi")
 	{
 		// Rotas Públicas
@@ -31,17 +36,46 @@ func New() *gin.Engine {
 		api.GET("/profile/:slug/is-owner", middleware.Auth, handler.CheckProfileOwnership) // usuário logado
 		api.GET("/profile/:slug/contact", middleware.Auth, handler.GetProfileContact)      // usuário logado
 
-		api.GET("/admin/stats", middleware.AdminAuth, handler.GetDashboardStats) // usuário admin
+		api.GET("/admin/stats", middleware.Require(models.PermStatsRead), handler.GetDashboardStats) // equipe
+		api.GET("/admin/audit-log", middleware.Require(models.PermAuditRead), handler.GetAuditLog)   // equipe
+
+		// Eventos em tempo real (Server-Sent Events)
+		api.GET("/events", middleware.Auth, handler.StreamEvents) // usuário logado
+
+		// Reconciliação entre o storage e as imagens do banco
+		api.GET("/admin/storage/reconcile-runs", middleware.Require(models.PermStorageManage), handler.GetReconcileRuns)    // equipe
+		api.GET("/admin/storage/reconcile-runs/:id", middleware.Require(models.PermStorageManage), handler.GetReconcileRun) // equipe
+		api.POST("/admin/storage/reconcile", middleware.Require(models.PermStorageManage), handler.TriggerReconcile)        // equipe
+
+		// Arquivos do storage local (STORAGE_BACKEND=local), no lugar do S3
+		if config.LocalStorage != nil {
+			api.GET("/files/*key", handler.ServeLocalFile)  // qualquer usuário
+			api.PUT("/files/*key", handler.UploadLocalFile) // URL pré-assinada
+		}
 
 		userRouter := api.Group("/users")
 		userRouter.Use(middleware.Auth)
 		{
-			userRouter.GET("/me", handler.GetUser)                                       // usuário logado
-			userRouter.PUT("/me", handler.UpdateUser)                                    // usuário logado
-			userRouter.DELETE("/me", handler.DeleteUser)                                 // usuário logado
-			userRouter.GET("/", middleware.AdminAuth, handler.GetUsers)                  // usuário admin
-			userRouter.DELETE("/:slug", middleware.AdminAuth, handler.DeleteUserByAdmin) // usuário admin
-			userRouter.PUT("/:slug/role", middleware.AdminAuth, handler.UpdateUserRole)  // usuário admin
+			userRouter.GET("/me", handler.GetUser)                                                                           // usuário logado
+			userRouter.PUT("/me", handler.UpdateUser)                                                                        // usuário logado
+			userRouter.DELETE("/me", handler.DeleteUser)                                                                     // usuário logado
+			userRouter.GET("/me/blocks", handler.GetBlockedUsers)                                                            // usuário logado
+			userRouter.GET("/me/notification-preferences", handler.GetNotificationPreferences)                               // usuário logado
+			userRouter.PUT("/me/notification-preferences", handler.UpdateNotificationPreferences)                            // usuário logado
+			userRouter.POST("/me/verification", handler.StartVerification)                                                   // usuário logado
+			userRouter.POST("/me/verification/confirm", handler.ConfirmVerification)                                         // usuário logado
+			userRouter.POST("/:slug/block", handler.BlockUser)                                                               // usuário logado
+			userRouter.DELETE("/:slug/block", handler.UnblockUser)                                                           // usuário logado
+			userRouter.GET("/", middleware.Require(models.PermUsersRead), handler.GetUsers)                                  // equipe
+			userRouter.DELETE("/:slug", middleware.Require(models.PermUsersDelete), handler.DeleteUserByAdmin)               // equipe
+			userRouter.PUT("/:slug/role", middleware.Require(models.PermRolesGrant), handler.UpdateUserRole)                 // equipe
+			userRouter.GET("/:slug/sanctions", middleware.Require(models.PermUsersRead), handler.GetUserSanctions)           // equipe
+			userRouter.POST("/:slug/suspend", middleware.Require(models.PermUsersSuspend), handler.SuspendUser)              // equipe
+			userRouter.DELETE("/:slug/suspend", middleware.Require(models.PermUsersSuspend), handler.UnsuspendUser)          // equipe
+			userRouter.POST("/:slug/ban", middleware.Require(models.PermUsersSuspend), handler.BanUser)                      // equipe
+			userRouter.DELETE("/:slug/ban", middleware.Require(models.PermUsersSuspend), handler.UnbanUser)                  // equipe
+			userRouter.POST("/:slug/verification", middleware.Require(models.PermUsersVerify), handler.VerifyUser)           // equipe
+			userRouter.DELETE("/:slug/verification", middleware.Require(models.PermUsersVerify), handler.RevokeVerification) // equipe
 		}
 
 		listingRouter := api.Group("/listings")
@@ -59,11 +93,17 @@ func New() *gin.Engine {
 			listingRouter.PUT("/:id", handler.UpdateListing)
 			listingRouter.DELETE("/:id", handler.DeleteListing)
 			listingRouter.POST("/:id/sell", handler.CreateSale)
-
-			// apenas admins
-			listingRouter.GET("/admin", middleware.AdminAuth, handler.GetListingsAdmin)
-			listingRouter.DELETE("/admin/:id", middleware.AdminAuth, handler.DeleteListingByAdmin)
-			listingRouter.PUT("/admin/:id/status", middleware.AdminAuth, handler.UpdateListingStatusByAdmin)
+			listingRouter.POST("/:id/reserve", handler.ReserveListing)
+			listingRouter.DELETE("/:id/reserve", handler.ReleaseListing)
+			listingRouter.POST("/:id/offers", handler.CreateOffer)
+			listingRouter.GET("/:id/offers", handler.GetListingOffers)
+			listingRouter.POST("/:id/images", handler.UploadListingImage)
+			listingRouter.PUT("/:id/images/order", handler.ReorderListingImages)
+
+			// apenas a equipe
+			listingRouter.GET("/admin", middleware.Require(models.PermListingsModerate), handler.GetListingsAdmin)
+			listingRouter.DELETE("/admin/:id", middleware.Require(models.PermListingsModerate), handler.DeleteListingByAdmin)
+			listingRouter.PUT("/admin/:id/status", middleware.Require(models.PermListingsModerate), handler.UpdateListingStatusByAdmin)
 		}
 
 		salesRouter := api.Group("/sales")
@@ -72,24 +112,44 @@ func New() *gin.Engine {
 			salesRouter.GET("/:id", handler.GetSale)              // usuário logado
 			salesRouter.GET("/buyer", handler.GetSalesAsBuyer)    // usuário logado
 			salesRouter.GET("/seller", handler.GetSalesAsSeller)  // usuário logado
+			salesRouter.POST("/:id/accept", handler.AcceptSale)   // comprador
+			salesRouter.POST("/:id/reject", handler.RejectSale)   // comprador
+			salesRouter.POST("/:id/cancel", handler.CancelSale)   // vendedor
 			salesRouter.POST("/:id/review", handler.CreateReview) // usuário logado
 		}
 
 		reviewRouter := api.Group("/reviews")
 		{
-			reviewRouter.GET("/:user_slug/sent", handler.GetReviewsSent)         // qualquer usuário
-			reviewRouter.GET("/:user_slug/received", handler.GetReviewsReceived) // qualquer usuário
+			reviewRouter.GET("/:user_slug/sent", handler.GetReviewsSent)                                           // qualquer usuário
+			reviewRouter.GET("/:user_slug/received", handler.GetReviewsReceived)                                   // qualquer usuário
+			reviewRouter.POST("/:id/reply", middleware.Auth, handler.ReplyToReview)                                // usuário avaliado
+			reviewRouter.PUT("/:id", middleware.Auth, handler.UpdateReview)                                        // autor da avaliação
+			reviewRouter.DELETE("/:id", middleware.Auth, handler.DeleteReview)                                     // autor da avaliação
+			reviewRouter.PUT("/:id/hide", middleware.Require(models.PermReviewsModerate), handler.HideReview)      // equipe
+			reviewRouter.DELETE("/:id/hide", middleware.Require(models.PermReviewsModerate), handler.UnhideReview) // equipe
 		}
 
 		categorieRouter := api.Group("/categories")
 		{
-			categorieRouter.GET("/", handler.GetCategories)  // qualquer usuário
-			categorieRouter.GET("/:id", handler.GetCategory) // qualquer usuário
+			categorieRouter.GET("/", handler.GetCategories)       // qualquer usuário
+			categorieRouter.GET("/tree", handler.GetCategoryTree) // qualquer usuário
+			categorieRouter.GET("/:id", handler.GetCategory)      // qualquer usuário
+
+			categorieRouter.Use(middleware.Require(models.PermCategoriesManage))
+			categorieRouter.POST("/", handler.CreateCategory)      // equipe
+			categorieRouter.PUT("/:id", handler.UpdateCategory)    // equipe
+			categorieRouter.DELETE("/:id", handler.DeleteCategory) // equipe
+		}
+
+		institutionRouter := api.Group("/institutions")
+		{
+			institutionRouter.GET("/", handler.GetInstitutions) // qualquer usuário
 
-			categorieRouter.Use(middleware.AdminAuth)
-			categorieRouter.POST("/", handler.CreateCategory)      // usuário admin
-			categorieRouter.PUT("/:id", handler.UpdateCategory)    // usuário admin
-			categorieRouter.DELETE("/:id", handler.DeleteCategory) // usuário admin
+			institutionRouter.Use(middleware.Require(models.PermInstitutionsManage))
+			institutionRouter.GET("/admin", handler.GetInstitutionsAdmin) // equipe
+			institutionRouter.POST("/", handler.CreateInstitution)        // equipe
+			institutionRouter.PUT("/:id", handler.UpdateInstitution)      // equipe
+			institutionRouter.DELETE("/:id", handler.DeleteInstitution)   // equipe
 		}
 
 		listingImageRouter := api.Group("/listing-images")
@@ -114,15 +174,45 @@ func New() *gin.Engine {
 			favoriteRouter.GET("/:user_id", handler.ListFavoritesByUser) // usuário logado
 		}
 
+		offerRouter := api.Group("/offers")
+		offerRouter.Use(middleware.Auth)
+		{
+			offerRouter.GET("/", handler.GetMyOffers)                // usuário logado
+			offerRouter.POST("/:id/accept", handler.AcceptOffer)     // comprador ou vendedor
+			offerRouter.POST("/:id/reject", handler.RejectOffer)     // comprador ou vendedor
+			offerRouter.POST("/:id/counter", handler.CounterOffer)   // comprador ou vendedor
+			offerRouter.POST("/:id/withdraw", handler.WithdrawOffer) // comprador
+		}
+
+		conversationRouter := api.Group("/conversations")
+		conversationRouter.Use(middleware.Auth)
+		{
+			conversationRouter.GET("/", handler.GetConversations)                    // usuário logado
+			conversationRouter.POST("/", handler.StartConversation)                  // usuário logado
+			conversationRouter.GET("/:id", handler.GetConversation)                  // usuário logado
+			conversationRouter.GET("/:id/messages", handler.GetConversationMessages) // usuário logado
+			conversationRouter.POST("/:id/messages", handler.SendMessage)            // usuário logado
+			conversationRouter.POST("/:id/read", handler.MarkConversationRead)       // usuário logado
+		}
+
+		notificationRouter := api.Group("/notifications")
+		notificationRouter.Use(middleware.Auth)
+		{
+			notificationRouter.GET("/", handler.GetNotifications)              // usuário logado
+			notificationRouter.POST("/read", handler.MarkAllNotificationsRead) // usuário logado
+			notificationRouter.POST("/:id/read", handler.MarkNotificationRead) // usuário logado
+		}
+
 		reportRouter := api.Group("/reports")
 		{
 			reportRouter.Use(middleware.Auth)
 			reportRouter.POST("/", handler.CreateReport) // usuário logado
 
-			reportRouter.Use(middleware.AdminAuth)
-			reportRouter.GET("/", handler.GetReports)                   // usuário admin
-			reportRouter.GET("/:id", handler.GetReport)                 // usuário admin
-			reportRouter.PUT("/:id/status", handler.UpdateReportStatus) // usuário admin
+			reportRouter.GET("/", middleware.Require(models.PermReportsRead), handler.GetReports)                      // equipe
+			reportRouter.GET("/:id", middleware.Require(models.PermReportsRead), handler.GetReport)                    // equipe
+			reportRouter.PUT("/:id/status", middleware.Require(models.PermReportsResolve), handler.UpdateReportStatus) // equipe
+			reportRouter.PUT("/:id/assignee", middleware.Require(models.PermReportsResolve), handler.AssignReport)     // equipe
+			reportRouter.POST("/:id/notes", middleware.Require(models.PermReportsResolve), handler.AddReportNote)      // equipe
 		}
 	}
 
@@ -0,0 +1,138 @@
+// Package sales holds the sale workflow and listing reservations shared by
+// the handlers and the scheduled jobs: a pending sale reserves its listing
+// until the buyer answers, and sellers can put a listing on hold for a while.
+package sales
+
+import (
+	"api/internal/models"
+	"api/internal/notify"
+	"api/internal/repository"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProposalTTL is how long a buyer has to confirm a sale
+const ProposalTTL = 72 * time.Hour
+
+// Durations of a reservation made by the seller
+const (
+	DefaultReservation = 48 * time.Hour
+	MaxReservation     = 7 * 24 * time.Hour
+)
+
+// Release makes a reserved listing available again
+func Release(tx *gorm.DB, listingID uuid.UUID) error {
+	return tx.Model(&models.Listing{}).
+		Where("id = ? AND status = ?", listingID, models.Reserved).
+		Updates(map[string]any{
+			"status":          models.Available,
+			"reserved_for_id": nil,
+			"reserved_until":  nil,
+		}).Error
+}
+
+// HasPending reports whether the listing has a sale waiting for the buyer
+func HasPending(tx *gorm.DB, listingID uuid.UUID) (bool, error) {
+	var count int64
+	err := tx.Model(&models.Sale{}).Where("listing_id = ? AND status = ?", listingID, models.SalePending).Count(&count).Error
+	return count > 0, err
+}
+
+// Close ends a pending sale with status (rejected, cancelled or expired) and
+// releases its listing. Call it in a transaction holding the sale row lock.
+func Close(tx *gorm.DB, sale *models.Sale, status models.SaleStatus) error {
+	now := time.Now()
+	sale.Status = status
+	sale.RespondedAt = &now
+	if err := tx.Model(sale).Select("status", "responded_at").Updates(sale).Error; err != nil {
+		return err
+	}
+
+	return Release(tx, sale.ListingID)
+}
+
+// ExpirePending is the cron entry point: it expires the sales the buyer did
+// not answer in time and tells their sellers.
+func ExpirePending() {
+	var expired []models.Sale
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Preload("Listing").
+			Where("status = ? AND expires_at <= ?", models.SalePending, time.Now()).
+			Find(&expired).Error; err != nil {
+			return err
+		}
+
+		for i := range expired {
+			sale := &expired[i]
+			if err := Close(tx, sale, models.SaleExpired); err != nil {
+				return err
+			}
+			if err := notify.Send(tx, sale.SellerID, models.NotificationSaleClosed, models.JSONMap{
+				"sale_id":       sale.ID,
+				"listing_id":    sale.ListingID,
+				"listing_title": sale.Listing.Title,
+				"status":        sale.Status,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("❌ failed to expire pending sales: %v", err)
+		return
+	}
+	if len(expired) > 0 {
+		log.Printf("✅ expired %d pending sales", len(expired))
+	}
+}
+
+// ReleaseExpiredReservations is the cron entry point: it makes the listings
+// whose reservation ended available again. Listings with a pending sale are
+// left to ExpirePending.
+func ReleaseExpiredReservations() {
+	result := repository.DB.Model(&models.Listing{}).
+		Where("status = ? AND reserved_until <= ?", models.Reserved, time.Now()).
+		Where("NOT EXISTS (SELECT 1 FROM sales WHERE sales.listing_id = listings.id AND sales.status = ?)", models.SalePending).
+		Updates(map[string]any{
+			"status":          models.Available,
+			"reserved_for_id": nil,
+			"reserved_until":  nil,
+		})
+	if result.Error != nil {
+		log.Printf("❌ failed to release expired reservations: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("✅ released %d expired reservations", result.RowsAffected)
+	}
+}
+
+// OfferTTL is how long the other party has to answer a proposal
+const OfferTTL = 48 * time.Hour
+
+// CloseOpenOffers rejects the open offers of a listing that was sold
+func CloseOpenOffers(tx *gorm.DB, listingID uuid.UUID) error {
+	return tx.Model(&models.Offer{}).
+		Where("listing_id = ? AND status = ?", listingID, models.OfferOpen).
+		Updates(map[string]any{"status": models.OfferRejected, "answered_at": time.Now()}).Error
+}
+
+// ExpireOffers is the cron entry point: it expires the proposals nobody answered in time
+func ExpireOffers() {
+	result := repository.DB.Model(&models.Offer{}).
+		Where("status = ? AND expires_at <= ?", models.OfferOpen, time.Now()).
+		Update("status", models.OfferExpired)
+	if result.Error != nil {
+		log.Printf("❌ failed to expire offers: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("✅ expired %d offers", result.RowsAffected)
+	}
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned by VerifyPresign for forged or expired URLs.
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// Local stores objects as files under Root. Files are served, and presigned
+// uploads received, by the API itself under PublicBaseURL.
+type Local struct {
+	Root          string
+	PublicBaseURL string // e.g. http://localhost:8080/brechoapi/files
+	signingKey    []byte
+}
+
+// NewLocal creates the root directory if needed.
+func NewLocal(root, publicBaseURL, signingKey string) (*Local, error) {
+	if signingKey == "" {
+		return nil, errors.New("local storage requires a signing key")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{Root: root, PublicBaseURL: strings.TrimRight(publicBaseURL, "/"), signingKey: []byte(signingKey)}, nil
+}
+
+// Path returns the file path of key, confined to Root.
+func (l *Local) Path(key string) (string, error) {
+	clean := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if clean == "" {
+		return "", ErrNotFound
+	}
+	return filepath.Join(l.Root, filepath.FromSlash(clean)), nil
+}
+
+func (l *Local) Put(ctx context.Context, key, contentType string, body io.Reader) error {
+	p, err := l.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temp file first so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.Path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (string, error) {
+	exp := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("content_type", contentType)
+	query.Set("expires", exp)
+	query.Set("signature", l.sign(key, contentType, exp))
+
+	return fmt.Sprintf("%s?%s", l.PublicURL(key), query.Encode()), nil
+}
+
+// VerifyPresign checks the query of a URL returned by PresignPut.
+func (l *Local) VerifyPresign(key, contentType, expires, signature string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(key, contentType, expires))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (l *Local) sign(key, contentType, expires string) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	mac.Write([]byte(key + "\n" + contentType + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) DeleteMany(ctx context.Context, keys []string) map[string]error {
+	failed := make(map[string]error)
+	for _, key := range keys {
+		if err := l.Delete(ctx, key); err != nil {
+			failed[key] = err
+		}
+	}
+	return failed
+}
+
+func (l *Local) List(ctx context.Context) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(l.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(l.Root, p)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{
+			Key:          filepath.ToSlash(rel),
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (l *Local) PublicURL(key string) string {
+	return fmt.Sprintf("%s/%s", l.PublicBaseURL, key)
+}
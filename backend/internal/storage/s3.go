@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Options configures an S3 backend. Endpoint, PublicBaseURL and UsePathStyle
+// are only needed for S3-compatible servers (MinIO, R2, ...).
+type S3Options struct {
+	Region        string
+	Bucket        string
+	Endpoint      string // e.g. http://minio:9000, empty for AWS
+	PublicBaseURL string // base of the public URLs, defaults to the bucket URL
+	UsePathStyle  bool
+}
+
+// S3 stores objects in an S3 bucket.
+type S3 struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	opts    S3Options
+}
+
+// NewS3 builds an S3 backend from the default AWS credential chain.
+func NewS3(ctx context.Context, opts S3Options) (*S3, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(opts.Region))
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+		o.UsePathStyle = opts.UsePathStyle
+	})
+
+	if opts.PublicBaseURL == "" {
+		switch {
+		case opts.Endpoint == "":
+			opts.PublicBaseURL = fmt.Sprintf("https://%s.s3.amazonaws.com", opts.Bucket)
+		case opts.UsePathStyle:
+			opts.PublicBaseURL = fmt.Sprintf("%s/%s", strings.TrimRight(opts.Endpoint, "/"), opts.Bucket)
+		default:
+			// Virtual-hosted style: the bucket is a subdomain of the endpoint
+			endpoint, err := url.Parse(opts.Endpoint)
+			if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+				return nil, fmt.Errorf("invalid S3 endpoint %q", opts.Endpoint)
+			}
+			endpoint.Host = opts.Bucket + "." + endpoint.Host
+			opts.PublicBaseURL = strings.TrimRight(endpoint.String(), "/")
+		}
+	}
+
+	return &S3{client: client, presign: s3.NewPresignClient(client), opts: opts}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key, contentType string, body io.Reader) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.opts.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	})
+	return err
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.opts.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (s *S3) PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (string, error) {
+	req, err := s.presign.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.opts.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.opts.Bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// deleteBatchSize is the maximum number of keys per DeleteObjects request
+const deleteBatchSize = 1000
+
+func (s *S3) DeleteMany(ctx context.Context, keys []string) map[string]error {
+	failed := make(map[string]error)
+
+	for start := 0; start < len(keys); start += deleteBatchSize {
+		batch := keys[start:min(start+deleteBatchSize, len(keys))]
+
+		objects := make([]types.ObjectIdentifier, len(batch))
+		for i, key := range batch {
+			objects[i] = types.ObjectIdentifier{Key: aws.String(key)}
+		}
+
+		out, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.opts.Bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			for _, key := range batch {
+				failed[key] = err
+			}
+			continue
+		}
+		for _, e := range out.Errors {
+			failed[aws.ToString(e.Key)] = fmt.Errorf("%s: %s", aws.ToString(e.Code), aws.ToString(e.Message))
+		}
+	}
+
+	return failed
+}
+
+func (s *S3) List(ctx context.Context) ([]Object, error) {
+	var objects []Object
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.opts.Bucket),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			objects = append(objects, Object{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+func (s *S3) PublicURL(key string) string {
+	return fmt.Sprintf("%s/%s", strings.TrimRight(s.opts.PublicBaseURL, "/"), key)
+}
@@ -0,0 +1,39 @@
+// Package storage abstracts where listing images are kept (AWS S3, an
+// S3-compatible server such as MinIO, or the local disk).
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when a key does not exist in the storage.
+var ErrNotFound = errors.New("object not found")
+
+// Object describes a stored object.
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Storage is implemented by every storage backend.
+type Storage interface {
+	// Put stores body under key.
+	Put(ctx context.Context, key, contentType string, body io.Reader) error
+	// Get opens key for reading, or returns ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// PresignPut returns a URL the client can PUT the object to, valid for expires.
+	PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (string, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeleteMany removes keys in batches and returns the error of each key
+	// that could not be deleted.
+	DeleteMany(ctx context.Context, keys []string) map[string]error
+	// List returns every object in the storage.
+	List(ctx context.Context) ([]Object, error)
+	// PublicURL returns the URL the app uses to reference key.
+	PublicURL(key string) string
+}